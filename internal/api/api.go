@@ -7,8 +7,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,12 +20,33 @@ const (
 	submissionPath = "/api/project"
 )
 
-var ErrAlreadyRegistered = errors.New("project already submitted")
+// Errors returned by the API client.
+var (
+	ErrAlreadyRegistered = errors.New("project already submitted")
+	ErrNotRegistered     = errors.New("project not registered")
+	ErrUnreachable       = errors.New("API unreachable")
+)
+
+// DefaultTimeout bounds each request when no timeout or HTTP client is given.
+const DefaultTimeout = 15 * time.Second
 
 // Client wraps interactions with the blazingly.fast API.
 type Client struct {
 	baseURL    string
+	token      string
 	httpClient *http.Client
+	retry      RetryPolicy
+	attempts   atomic.Int64
+}
+
+// Config configures a Client. Zero values fall back to defaults.
+type Config struct {
+	BaseURL    string
+	Token      string
+	Timeout    time.Duration
+	Transport  http.RoundTripper
+	HTTPClient *http.Client
+	Retry      RetryPolicy
 }
 
 // Submission mirrors the backend submission form.
@@ -31,6 +55,9 @@ type Submission struct {
 	IsBlazinglyFast bool
 	Blurb           string
 	Hidden          bool
+	// Challenge is a verified ownership challenge token. When set, the API
+	// marks the registration as verified.
+	Challenge string
 }
 
 // SubmissionResponse is a subset of the API payload.
@@ -39,33 +66,77 @@ type SubmissionResponse struct {
 	Project json.RawMessage `json:"project"`
 }
 
-// Error represents a non-409 API error.
+// Project is the server-side record for a registered repository.
+type Project struct {
+	ID      string `json:"id"`
+	RepoURL string `json:"repoUrl"`
+	Blurb   string `json:"blurb"`
+	Hidden  bool   `json:"hidden"`
+}
+
+// ProjectUpdate lists the fields to change on an existing registration. Nil
+// fields are left as they are on the server.
+type ProjectUpdate struct {
+	RepoURL string
+	Blurb   *string
+	Hidden  *bool
+}
+
+// Error represents an API error response. For a 409 on submit or a 404 on
+// lookup and update, Err is ErrAlreadyRegistered or ErrNotRegistered, so
+// errors.Is matches those sentinels while the status and server message
+// stay available.
 type Error struct {
 	Status  int
 	Message string
+	Err     error
 }
 
 func (e *Error) Error() string {
 	if e == nil {
 		return ""
 	}
+	if e.Err != nil {
+		return e.Err.Error()
+	}
 	if e.Message == "" {
 		return fmt.Sprintf("api request failed with status %d", e.Status)
 	}
 	return fmt.Sprintf("api request failed: %s (status %d)", e.Message, e.Status)
 }
 
+func (e *Error) Unwrap() error { return e.Err }
+
 // NewClient builds a client using the provided base URL.
 func NewClient(baseURL string, httpClient *http.Client) *Client {
+	return New(Config{BaseURL: baseURL, HTTPClient: httpClient})
+}
+
+// New builds a client from cfg. When HTTPClient is nil a default client is
+// created using Timeout, or DefaultTimeout if that is zero, and Transport,
+// or http.DefaultTransport if that is nil. Zero Retry fields fall back to
+// DefaultRetryPolicy.
+func New(cfg Config) *Client {
+	baseURL := cfg.BaseURL
 	if baseURL == "" {
 		baseURL = DefaultBaseURL
 	}
 
+	httpClient := cfg.HTTPClient
 	if httpClient == nil {
-		httpClient = &http.Client{Timeout: 15 * time.Second}
+		timeout := cfg.Timeout
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		httpClient = &http.Client{Timeout: timeout, Transport: cfg.Transport}
 	}
 
-	return &Client{baseURL: strings.TrimRight(baseURL, "/"), httpClient: httpClient}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      cfg.Token,
+		httpClient: httpClient,
+		retry:      cfg.Retry.withDefaults(),
+	}
 }
 
 // Submit sends the submission payload to the API.
@@ -76,46 +147,184 @@ func (c *Client) Submit(ctx context.Context, payload Submission) (*SubmissionRes
 		"blurb":           payload.Blurb,
 		"hidden":          payload.Hidden,
 	}
+	if payload.Challenge != "" {
+		body["challenge"] = payload.Challenge
+	}
 
-	buf := &bytes.Buffer{}
-	if err := json.NewEncoder(buf).Encode(body); err != nil {
+	buf, err := json.Marshal(body)
+	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+submissionPath, buf)
+	status, data, err := c.do(ctx, http.MethodPost, submissionPath, buf)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "bfast-cli")
 
-	resp, err := c.httpClient.Do(req)
+	if status == http.StatusConflict {
+		return nil, &Error{Status: status, Message: extractMessage(data), Err: ErrAlreadyRegistered}
+	}
+
+	if status >= 400 {
+		return nil, &Error{Status: status, Message: extractMessage(data)}
+	}
+
+	var submission SubmissionResponse
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &submission); err != nil {
+			return nil, err
+		}
+	}
+
+	return &submission, nil
+}
+
+// Lookup fetches the registration for the given repo URL. It returns
+// ErrNotRegistered when the API has no record of the repo.
+func (c *Client) Lookup(ctx context.Context, repoURL string) (*Project, error) {
+	query := url.Values{"repoUrl": {repoURL}}
+	status, data, err := c.do(ctx, http.MethodGet, submissionPath+"?"+query.Encode(), nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	data, err := io.ReadAll(resp.Body)
+	if status == http.StatusNotFound {
+		return nil, &Error{Status: status, Message: extractMessage(data), Err: ErrNotRegistered}
+	}
+
+	if status >= 400 {
+		return nil, &Error{Status: status, Message: extractMessage(data)}
+	}
+
+	return decodeProject(data)
+}
+
+// Update edits an existing registration. It returns ErrNotRegistered when the
+// API has no record of the repo.
+func (c *Client) Update(ctx context.Context, payload ProjectUpdate) (*Project, error) {
+	body := map[string]interface{}{
+		"repoUrl": payload.RepoURL,
+	}
+	if payload.Blurb != nil {
+		body["blurb"] = *payload.Blurb
+	}
+	if payload.Hidden != nil {
+		body["hidden"] = *payload.Hidden
+	}
+
+	buf, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode == http.StatusConflict {
-		return nil, ErrAlreadyRegistered
+	status, data, err := c.do(ctx, http.MethodPatch, submissionPath, buf)
+	if err != nil {
+		return nil, err
 	}
 
-	if resp.StatusCode >= 400 {
-		return nil, &Error{Status: resp.StatusCode, Message: extractMessage(data)}
+	if status == http.StatusNotFound {
+		return nil, &Error{Status: status, Message: extractMessage(data), Err: ErrNotRegistered}
 	}
 
-	var submission SubmissionResponse
-	if len(data) > 0 {
-		if err := json.Unmarshal(data, &submission); err != nil {
-			return nil, err
+	if status >= 400 {
+		return nil, &Error{Status: status, Message: extractMessage(data)}
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	return decodeProject(data)
+}
+
+// decodeProject accepts either a bare project object or one wrapped in a
+// "project" field.
+func decodeProject(data []byte) (*Project, error) {
+	var wrapped struct {
+		Project *Project `json:"project"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, err
+	}
+	if wrapped.Project != nil {
+		return wrapped.Project, nil
+	}
+
+	var project Project
+	if err := json.Unmarshal(data, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// Attempts reports how many HTTP requests the client has sent, counting
+// retries.
+func (c *Client) Attempts() int {
+	return int(c.attempts.Load())
+}
+
+// do sends a request, retrying under the client's RetryPolicy, and returns
+// the status code and the full response body of the last attempt.
+func (c *Client) do(ctx context.Context, method, path string, body []byte) (int, []byte, error) {
+	for attempt := 1; ; attempt++ {
+		status, header, data, err := c.send(ctx, method, path, body)
+		if attempt >= c.retry.MaxAttempts || !retryable(ctx, method, status, err) {
+			return status, data, err
+		}
+
+		wait, ok := c.retry.delay(attempt, header)
+		if !ok {
+			return status, data, err
+		}
+		slog.Debug("retrying api request", "method", method, "path", path, "attempt", attempt,
+			"status", status, "error", err, "wait", wait)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return status, data, err
+		case <-timer.C:
 		}
 	}
+}
 
-	return &submission, nil
+// send makes a single attempt.
+func (c *Client) send(ctx context.Context, method, path string, body []byte) (int, http.Header, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", "bfast-cli")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	c.attempts.Add(1)
+	slog.Debug("api request", "method", method, "url", req.URL.String(), "auth", c.token != "")
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		slog.Debug("api request failed", "method", method, "url", req.URL.String(), "error", err)
+		return 0, nil, nil, fmt.Errorf("%w: %w", ErrUnreachable, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("%w: %w", ErrUnreachable, err)
+	}
+	slog.Debug("api response", "method", method, "url", req.URL.String(), "status", resp.StatusCode,
+		"bytes", len(data), "duration", time.Since(start))
+
+	return resp.StatusCode, resp.Header, data, nil
 }
 
 func extractMessage(body []byte) string {
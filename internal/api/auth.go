@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	deviceCodePath  = "/api/auth/device/code"
+	deviceTokenPath = "/api/auth/device/token"
+	deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+// defaultPollInterval is used when the server does not say how often to
+// poll for the token.
+const defaultPollInterval = 5 * time.Second
+
+// Errors returned while completing a device login.
+var (
+	ErrLoginDenied  = errors.New("login was denied")
+	ErrLoginExpired = errors.New("login code expired before it was approved")
+)
+
+// DeviceCode is the server's answer to a device login request, following
+// the OAuth 2.0 device authorization grant (RFC 8628).
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                *int   `json:"interval,omitempty"`
+}
+
+// RequestDeviceCode starts a device login.
+func (c *Client) RequestDeviceCode(ctx context.Context) (*DeviceCode, error) {
+	body, err := json.Marshal(map[string]string{"client_id": "bfast-cli"})
+	if err != nil {
+		return nil, err
+	}
+
+	status, data, err := c.do(ctx, http.MethodPost, deviceCodePath, body)
+	if err != nil {
+		return nil, err
+	}
+	if status >= 400 {
+		return nil, &Error{Status: status, Message: extractMessage(data)}
+	}
+
+	var code DeviceCode
+	if err := json.Unmarshal(data, &code); err != nil {
+		return nil, fmt.Errorf("invalid device code response: %w", err)
+	}
+	if code.DeviceCode == "" || code.UserCode == "" || code.VerificationURI == "" {
+		return nil, errors.New("invalid device code response: missing fields")
+	}
+	return &code, nil
+}
+
+// PollDeviceToken waits for the user to approve the device login and returns
+// the access token. It stops when the code expires, the login is denied, or
+// ctx is done.
+func (c *Client) PollDeviceToken(ctx context.Context, code *DeviceCode) (string, error) {
+	interval := defaultPollInterval
+	if code.Interval != nil {
+		interval = time.Duration(*code.Interval) * time.Second
+	}
+	var deadline <-chan time.Time
+	if code.ExpiresIn > 0 {
+		timer := time.NewTimer(time.Duration(code.ExpiresIn) * time.Second)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"client_id":   "bfast-cli",
+		"device_code": code.DeviceCode,
+		"grant_type":  deviceGrantType,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		wait := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return "", ctx.Err()
+		case <-deadline:
+			wait.Stop()
+			return "", ErrLoginExpired
+		case <-wait.C:
+		}
+
+		status, data, err := c.do(ctx, http.MethodPost, deviceTokenPath, body)
+		if err != nil {
+			return "", err
+		}
+
+		var payload struct {
+			AccessToken string `json:"access_token"`
+			Error       string `json:"error"`
+		}
+		_ = json.Unmarshal(data, &payload)
+
+		switch {
+		case status < 300 && payload.AccessToken != "":
+			return payload.AccessToken, nil
+		case payload.Error == "authorization_pending":
+		case payload.Error == "slow_down":
+			interval += 5 * time.Second
+		case payload.Error == "access_denied":
+			return "", ErrLoginDenied
+		case payload.Error == "expired_token":
+			return "", ErrLoginExpired
+		default:
+			return "", &Error{Status: status, Message: extractMessage(data)}
+		}
+	}
+}
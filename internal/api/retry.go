@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how the client retries failed requests.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// 1 disables retries.
+	MaxAttempts int
+	// BaseDelay is the backoff ceiling after the first failure. It doubles
+	// with each attempt, and the actual wait is a random fraction of it.
+	BaseDelay time.Duration
+	// MaxDelay caps every wait. A Retry-After longer than this ends the
+	// retries instead of being shortened.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is used for any RetryPolicy field left at zero.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultRetryPolicy.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultRetryPolicy.MaxDelay
+	}
+	return p
+}
+
+// delay returns how long to wait after the given failed attempt. A valid
+// Retry-After header wins over the computed backoff. It reports false when
+// the server asks for a longer wait than MaxDelay allows.
+func (p RetryPolicy) delay(attempt int, header http.Header) (time.Duration, bool) {
+	if wait, ok := retryAfter(header, time.Now()); ok {
+		return wait, wait <= p.MaxDelay
+	}
+
+	ceiling := p.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+	// Full jitter: spread concurrent clients (bfast batch) across the window.
+	return rand.N(ceiling) + 1, true
+}
+
+// retryAfter parses a Retry-After header given as seconds or an HTTP date.
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := at.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// retryable reports whether a failed attempt may be repeated without risking
+// a duplicate side effect. 429 and 503 mean the server did not act on the
+// request, and a failed dial means it never arrived, so those are retried
+// for every method. Other transport errors and 502/504 leave the outcome
+// unknown, so they are only retried for idempotent requests.
+func retryable(ctx context.Context, method string, status int, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	// PATCH counts as idempotent here because Update sends absolute values.
+	idempotent := method == http.MethodGet || method == http.MethodPatch
+
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return true
+		}
+		return idempotent
+	}
+
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
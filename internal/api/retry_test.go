@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryAfterParsesSecondsAndDates(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	wait, ok := retryAfter(http.Header{"Retry-After": {"3"}}, now)
+	if !ok || wait != 3*time.Second {
+		t.Fatalf("seconds: got %v, %t", wait, ok)
+	}
+
+	date := now.Add(90 * time.Second).Format(http.TimeFormat)
+	wait, ok = retryAfter(http.Header{"Retry-After": {date}}, now)
+	if !ok || wait != 90*time.Second {
+		t.Fatalf("date: got %v, %t", wait, ok)
+	}
+
+	if _, ok := retryAfter(http.Header{"Retry-After": {"soon"}}, now); ok {
+		t.Fatal("expected an invalid Retry-After to be ignored")
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt := 1; attempt <= 6; attempt++ {
+		wait, ok := p.delay(attempt, http.Header{})
+		if !ok || wait <= 0 || wait > time.Second {
+			t.Fatalf("attempt %d: wait %v out of range", attempt, wait)
+		}
+	}
+
+	if _, ok := p.delay(1, http.Header{"Retry-After": {"120"}}); ok {
+		t.Fatal("a Retry-After beyond MaxDelay should stop retrying")
+	}
+}
+
+func TestRetryableOnlyRepeatsSafeFailures(t *testing.T) {
+	ctx := context.Background()
+	dialErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	readErr := &net.OpError{Op: "read", Err: errors.New("connection reset")}
+
+	cases := []struct {
+		method string
+		status int
+		err    error
+		want   bool
+	}{
+		{http.MethodPost, http.StatusServiceUnavailable, nil, true},
+		{http.MethodPost, http.StatusTooManyRequests, nil, true},
+		{http.MethodPost, http.StatusBadGateway, nil, false},
+		{http.MethodGet, http.StatusBadGateway, nil, true},
+		{http.MethodPost, http.StatusInternalServerError, nil, false},
+		{http.MethodPost, http.StatusConflict, nil, false},
+		{http.MethodPost, 0, dialErr, true},
+		{http.MethodPost, 0, readErr, false},
+		{http.MethodPatch, 0, readErr, true},
+	}
+
+	for _, tc := range cases {
+		if got := retryable(ctx, tc.method, tc.status, tc.err); got != tc.want {
+			t.Errorf("retryable(%s, %d, %v) = %t, want %t", tc.method, tc.status, tc.err, got, tc.want)
+		}
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if retryable(cancelled, http.MethodGet, http.StatusServiceUnavailable, nil) {
+		t.Fatal("a cancelled context should stop retries")
+	}
+}
+
+func TestSubmitRetriesServiceUnavailable(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	client := New(Config{BaseURL: srv.URL})
+	if _, err := client.Submit(context.Background(), Submission{RepoURL: "https://github.com/a/b"}); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if client.Attempts() != 3 {
+		t.Fatalf("attempts = %d, want 3", client.Attempts())
+	}
+
+	calls = 0
+	client = New(Config{BaseURL: srv.URL, Retry: RetryPolicy{MaxAttempts: 1}})
+	var apiErr *Error
+	if _, err := client.Submit(context.Background(), Submission{}); !errors.As(err, &apiErr) || apiErr.Status != http.StatusServiceUnavailable {
+		t.Fatalf("expected a 503 without retries, got %v", err)
+	}
+	if client.Attempts() != 1 {
+		t.Fatalf("attempts = %d, want 1", client.Attempts())
+	}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	challengePath = "/api/project/challenge"
+	verifyPath    = "/api/project/verify"
+)
+
+// DefaultChallengeFile is where the challenge token is written when the
+// server does not name a path.
+const DefaultChallengeFile = ".well-known/blazingly-fast"
+
+// Challenge is an ownership challenge for a repository. Its Token must be
+// published at Path in the repository before calling Verify.
+type Challenge struct {
+	Token     string `json:"challenge"`
+	Path      string `json:"path"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
+}
+
+// Verification is the result of a Verify call.
+type Verification struct {
+	Verified bool   `json:"verified"`
+	Message  string `json:"message,omitempty"`
+}
+
+// RequestChallenge asks the API for an ownership challenge for repoURL.
+func (c *Client) RequestChallenge(ctx context.Context, repoURL string) (*Challenge, error) {
+	body, err := json.Marshal(map[string]string{"repoUrl": repoURL})
+	if err != nil {
+		return nil, err
+	}
+
+	status, data, err := c.do(ctx, http.MethodPost, challengePath, body)
+	if err != nil {
+		return nil, err
+	}
+	if status >= 400 {
+		return nil, &Error{Status: status, Message: extractMessage(data)}
+	}
+
+	var challenge Challenge
+	if err := json.Unmarshal(data, &challenge); err != nil {
+		return nil, fmt.Errorf("invalid challenge response: %w", err)
+	}
+	if challenge.Token == "" {
+		return nil, fmt.Errorf("invalid challenge response: missing challenge")
+	}
+	if challenge.Path == "" {
+		challenge.Path = DefaultChallengeFile
+	}
+	return &challenge, nil
+}
+
+// Verify asks the API to check that the challenge token is published in the
+// repository at ref, or the default branch when ref is empty.
+func (c *Client) Verify(ctx context.Context, repoURL, token, ref string) (*Verification, error) {
+	payload := map[string]string{"repoUrl": repoURL, "challenge": token}
+	if ref != "" {
+		payload["ref"] = ref
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	status, data, err := c.do(ctx, http.MethodPost, verifyPath, body)
+	if err != nil {
+		return nil, err
+	}
+	if status >= 400 {
+		return nil, &Error{Status: status, Message: extractMessage(data)}
+	}
+
+	var verification Verification
+	if err := json.Unmarshal(data, &verification); err != nil {
+		return nil, fmt.Errorf("invalid verify response: %w", err)
+	}
+	return &verification, nil
+}
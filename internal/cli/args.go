@@ -0,0 +1,228 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// shorthands maps single-letter flags to the long flag they stand for. Both
+// are registered on the FlagSet; usage lists them on one line.
+var shorthands = map[string]string{
+	"m": "blurb",
+	"v": "verbose",
+}
+
+// boolFlag is implemented by flag values that do not take an argument.
+type boolFlag interface {
+	IsBoolFlag() bool
+}
+
+// scanArgs sets the flags in args on fs and returns the positional
+// arguments. Unlike fs.Parse it accepts flags after positionals, so
+// "bfast owner/repo --hidden" works. It understands --name=value,
+// --name value, clustered short flags such as -vm "text", the single-dash
+// long form of the flag package (-name), and -- to end flag parsing.
+func scanArgs(cmd *command, fs *flag.FlagSet, args []string) ([]string, error) {
+	var positionals []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return append(positionals, args[i+1:]...), nil
+		case arg == "-" || !strings.HasPrefix(arg, "-"):
+			positionals = append(positionals, arg)
+			continue
+		}
+
+		if strings.HasPrefix(arg, "---") {
+			return positionals, fmt.Errorf("invalid flag %s: use - or -- before the name", arg)
+		}
+		dash := "-"
+		if strings.HasPrefix(arg, "--") {
+			dash = "--"
+		}
+		long := dash == "--"
+		body := arg[len(dash):]
+		name, value, hasValue := strings.Cut(body, "=")
+
+		if name == "h" || name == "help" {
+			fs.Usage()
+			return positionals, flag.ErrHelp
+		}
+
+		// A single dash with a multi-letter name is a cluster of short flags
+		// unless it names a long flag, which the flag package allowed.
+		if !long && len(name) > 1 && fs.Lookup(name) == nil {
+			consumed, err := scanCluster(cmd, fs, body, args[i+1:])
+			if err != nil {
+				return positionals, err
+			}
+			i += consumed
+			continue
+		}
+
+		f := fs.Lookup(name)
+		if f == nil {
+			return positionals, unknownFlag(cmd, fs, dash, name)
+		}
+		if !hasValue {
+			if isBool(f) {
+				value = "true"
+			} else {
+				if i+1 >= len(args) {
+					return positionals, fmt.Errorf("flag %s%s needs a value", dash, name)
+				}
+				i++
+				value = args[i]
+			}
+		}
+		if err := setFlag(fs, dash, name, value); err != nil {
+			return positionals, err
+		}
+	}
+	return positionals, nil
+}
+
+// scanCluster sets each flag in a cluster such as "vm" from -vm. A flag that
+// takes a value uses the rest of the cluster, or the next argument when it
+// is last. It returns how many of rest were consumed.
+func scanCluster(cmd *command, fs *flag.FlagSet, cluster string, rest []string) (int, error) {
+	for j := 0; j < len(cluster); j++ {
+		name := cluster[j : j+1]
+		if name == "h" {
+			fs.Usage()
+			return 0, flag.ErrHelp
+		}
+		f := fs.Lookup(name)
+		if f == nil {
+			return 0, unknownFlag(cmd, fs, "-", name)
+		}
+		if isBool(f) {
+			if err := setFlag(fs, "-", name, "true"); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		value := strings.TrimPrefix(cluster[j+1:], "=")
+		consumed := 0
+		if value == "" {
+			if len(rest) == 0 {
+				return 0, fmt.Errorf("flag -%s needs a value", name)
+			}
+			value = rest[0]
+			consumed = 1
+		}
+		if err := setFlag(fs, "-", name, value); err != nil {
+			return 0, err
+		}
+		return consumed, nil
+	}
+	return 0, nil
+}
+
+// setFlag sets a flag and reports a bad value with the flag as the user
+// typed it.
+func setFlag(fs *flag.FlagSet, dash, name, value string) error {
+	err := fs.Set(name, value)
+	if err == nil {
+		return nil
+	}
+	if inner := errors.Unwrap(err); inner != nil {
+		err = inner
+	}
+	return fmt.Errorf("invalid value %q for %s%s: %v", value, dash, name, err)
+}
+
+func isBool(f *flag.Flag) bool {
+	b, ok := f.Value.(boolFlag)
+	return ok && b.IsBoolFlag()
+}
+
+// unknownFlag explains an unrecognized flag: it either belongs to another
+// command, or it is probably a typo of one of cmd's flags.
+func unknownFlag(cmd *command, fs *flag.FlagSet, dash, name string) error {
+	for _, other := range append([]*command{defaultCommand}, commands...) {
+		if other == cmd {
+			continue
+		}
+		for _, f := range other.flags {
+			if f == name {
+				return fmt.Errorf("unknown flag %s%s: %s accepts it, %s does not", dash, name, other.path(), cmd.path())
+			}
+		}
+	}
+
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if len(f.Name) > 1 {
+			names = append(names, f.Name)
+		}
+	})
+	if match := suggest(name, names); match != "" {
+		return fmt.Errorf("unknown flag %s%s; did you mean --%s?", dash, name, match)
+	}
+	return fmt.Errorf("unknown flag %s%s (see \"%s\")", dash, name, cmd.helpCommand())
+}
+
+// suggestCommand returns the command arg was probably meant to be. Words
+// that could be a repository, such as owner/repo or a URL, never match.
+func suggestCommand(arg string) string {
+	if strings.HasPrefix(arg, "-") || strings.ContainsAny(arg, "/:.") {
+		return ""
+	}
+	names := []string{"help"}
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+	return suggest(arg, names)
+}
+
+// suggest returns the candidate closest to input, or "" when none is close
+// enough to be a likely typo.
+func suggest(input string, candidates []string) string {
+	sort.Strings(candidates)
+	best, bestDist := "", -1
+	for _, candidate := range candidates {
+		dist := editDistance(input, candidate)
+		if strings.HasPrefix(candidate, input) && len(input) >= 3 {
+			dist = 1
+		}
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = candidate, dist
+		}
+	}
+
+	limit := 2
+	if len(input) <= 3 {
+		limit = 1
+	}
+	if bestDist < 0 || bestDist > limit {
+		return ""
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/arrno/bfast/internal/readme"
+)
+
+// runBadge inserts the badge into the README without calling the API.
+func runBadge(ctx context.Context, opts *options, stdout, stderr io.Writer) error {
+	tgt, err := resolveTarget(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	file, err := loadReadme(tgt, opts.readmeInput)
+	if err != nil {
+		return err
+	}
+
+	res := newResult(tgt, file, opts)
+	if err := checkFork(ctx, opts, tgt, res); err != nil {
+		return err
+	}
+
+	if readme.HasBadge(file.content) {
+		res.AlreadyBadged = true
+	} else {
+		badge := readme.BuildBadgeMarkdown(tgt.slug.Encoded())
+		res.BadgeMarkdown = badge
+
+		if opts.dryRun {
+			updated, err := readme.InsertBadgeAt(file.content, badge, readme.Placement(opts.placement))
+			if err != nil {
+				return err
+			}
+			res.Diff = readmeDiff(tgt, file, updated)
+		} else {
+			pr, err := prepareInsert(ctx, opts, tgt)
+			if err != nil {
+				return err
+			}
+			if err := insertBadge(ctx, opts, tgt, file, badge, pr, res); err != nil {
+				return err
+			}
+		}
+	}
+
+	if opts.json {
+		emitJSON(res, stdout)
+		return nil
+	}
+
+	printDiagnostics(stderr, res)
+	switch {
+	case res.AlreadyBadged:
+		fmt.Fprintln(stdout, "Already badged. No changes.")
+	case res.DryRun:
+		fmt.Fprintf(stdout, "Dry run: would update %s\n", res.Readme)
+		printDiff(stdout, res.Diff)
+	default:
+		fmt.Fprintf(stdout, "Badge added to %s\n", res.Readme)
+		printCommit(stdout, res)
+		printPullRequest(stdout, res)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+const defaultBatchJobs = 4
+
+var errBatchSource = errors.New("batch needs a directory or --repos-file, not both")
+
+type batchEntry struct {
+	Path   string  `json:"path"`
+	Result *result `json:"result,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// runBatch runs the default register-and-badge pipeline for many checkouts
+// with a bounded worker pool. A failing repo is recorded and does not stop
+// the others; the exit code is non-zero if any repo failed.
+func runBatch(ctx context.Context, opts *options, stdout, stderr io.Writer) error {
+	if (opts.batchDir == "") == (opts.reposFile == "") {
+		return errBatchSource
+	}
+	if opts.jobs < 1 {
+		return fmt.Errorf("--jobs must be at least 1, got %d", opts.jobs)
+	}
+
+	var paths []string
+	var err error
+	if opts.batchDir != "" {
+		paths, err = discoverRepos(opts.batchDir)
+	} else {
+		paths, err = readReposFile(opts.reposFile)
+	}
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("no git repositories found")
+	}
+
+	entries := make([]batchEntry, len(paths))
+	work := make(chan int)
+	var wg sync.WaitGroup
+
+	workers := opts.jobs
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				entries[i] = runBatchEntry(ctx, opts, paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	failed := 0
+	for _, entry := range entries {
+		if entry.Error != "" {
+			failed++
+		}
+	}
+
+	if opts.json {
+		emitJSON(entries, stdout)
+	} else {
+		printBatchDiagnostics(stderr, entries)
+		printBatchTable(stdout, entries)
+		fmt.Fprintf(stdout, "\n%d repositories, %d failed\n", len(entries), failed)
+	}
+
+	if failed > 0 {
+		return &exitError{code: exitFailure}
+	}
+	return nil
+}
+
+func runBatchEntry(ctx context.Context, opts *options, path string) batchEntry {
+	repoOpts := *opts
+	repoOpts.dir = path
+	repoOpts.repoInput = ""
+
+	entry := batchEntry{Path: path}
+	res, err := execute(ctx, &repoOpts)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.Result = res
+	return entry
+}
+
+// discoverRepos returns every directory under root that contains a .git
+// entry. It does not descend into a repository once found, nor into hidden
+// directories or node_modules.
+func discoverRepos(root string) ([]string, error) {
+	var repos []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		name := d.Name()
+		if path != root && (strings.HasPrefix(name, ".") || name == "node_modules") {
+			return filepath.SkipDir
+		}
+
+		if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+			repos = append(repos, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// readReposFile reads one repository path per line, skipping blank lines and
+// # comments.
+func readReposFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repos file: %w", err)
+	}
+	defer f.Close()
+
+	var repos []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		repos = append(repos, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read repos file: %w", err)
+	}
+	return repos, nil
+}
+
+// printBatchDiagnostics writes each repository's notices and warnings to
+// stderr like printDiagnostics, prefixed with its path.
+func printBatchDiagnostics(stderr io.Writer, entries []batchEntry) {
+	for _, entry := range entries {
+		if entry.Result == nil {
+			continue
+		}
+		for _, msg := range entry.Result.Notices {
+			fmt.Fprintf(stderr, "%s: %s\n", entry.Path, msg)
+		}
+		for _, msg := range entry.Result.Warnings {
+			fmt.Fprintf(stderr, "%s: Warning: %s\n", entry.Path, msg)
+		}
+	}
+}
+
+func printBatchTable(stdout io.Writer, entries []batchEntry) {
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tREPO\tSTATUS")
+	for _, entry := range entries {
+		repo := "-"
+		if entry.Result != nil {
+			repo = entry.Result.Repo
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", entry.Path, repo, batchStatus(entry))
+	}
+	_ = tw.Flush()
+}
+
+func batchStatus(entry batchEntry) string {
+	res := entry.Result
+	switch {
+	case entry.Error != "":
+		return "error: " + entry.Error
+	case res.AlreadyBadged:
+		return "already badged"
+	case res.DryRun:
+		return "dry run"
+	case res.RegistrationFailed != "":
+		return "badged, registration failed: " + res.RegistrationFailed
+	case res.AlreadyRegistered:
+		return "badged, already registered"
+	default:
+		return "registered and badged"
+	}
+}
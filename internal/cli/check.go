@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/arrno/bfast/internal/readme"
+)
+
+// Finding codes reported by bfast check.
+const (
+	findingMissing   = "missing_badge"
+	findingMismatch  = "repo_mismatch"
+	findingDuplicate = "duplicate_badge"
+)
+
+// Output formats accepted by --format.
+const (
+	formatText   = "text"
+	formatJSON   = "json"
+	formatGithub = "github"
+)
+
+type finding struct {
+	Code    string `json:"code"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+type checkResult struct {
+	Repo     string    `json:"repo"`
+	Readme   string    `json:"readme"`
+	Badges   int       `json:"badges"`
+	OK       bool      `json:"ok"`
+	Findings []finding `json:"findings"`
+}
+
+// runCheck lints the README badge for CI. It never calls the API or writes
+// anything, and exits with exitCheckFailed when there are findings.
+func runCheck(ctx context.Context, opts *options, stdout, stderr io.Writer) error {
+	format, err := checkFormat(opts)
+	if err != nil {
+		return err
+	}
+
+	tgt, err := resolveTarget(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	file, err := loadReadme(tgt, opts.readmeInput)
+	if err != nil {
+		return err
+	}
+
+	res := &checkResult{Repo: tgt.slug.String(), Readme: file.path}
+	res.Findings = checkBadges(file.content, tgt.slug.String())
+	res.Badges = len(readme.FindBadges(file.content))
+	res.OK = len(res.Findings) == 0
+
+	name := readmeName(tgt, file.path)
+	switch format {
+	case formatJSON:
+		emitJSON(res, stdout)
+	case formatGithub:
+		for _, f := range res.Findings {
+			printAnnotation(stdout, name, f)
+		}
+	default:
+		if res.OK {
+			fmt.Fprintf(stdout, "%s: badge OK for %s\n", name, res.Repo)
+		}
+		for _, f := range res.Findings {
+			if f.Line > 0 {
+				fmt.Fprintf(stdout, "%s:%d: %s\n", name, f.Line, f.Message)
+			} else {
+				fmt.Fprintf(stdout, "%s: %s\n", name, f.Message)
+			}
+		}
+	}
+
+	if !res.OK {
+		return &exitError{code: exitCheckFailed}
+	}
+	return nil
+}
+
+// checkBadges returns the problems with the badges in content for the repo
+// slug: a missing badge, badges pointing at another repo, and duplicates.
+func checkBadges(content, slug string) []finding {
+	findings := []finding{}
+	badges := readme.FindBadges(content)
+	if len(badges) == 0 {
+		// HasBadge also sees the badge URL outside markup FindBadges parses,
+		// such as a bare link; there is no repo= to compare then.
+		if !readme.HasBadge(content) {
+			return append(findings, finding{Code: findingMissing, Message: "blazingly.fast badge is missing"})
+		}
+		return findings
+	}
+
+	for i, badge := range badges {
+		switch {
+		case badge.Repo == "":
+			findings = append(findings, finding{
+				Code:    findingMismatch,
+				Line:    badge.Line,
+				Message: fmt.Sprintf("badge has no repo= parameter, want %s", slug),
+			})
+		case !strings.EqualFold(badge.Repo, slug):
+			findings = append(findings, finding{
+				Code:    findingMismatch,
+				Line:    badge.Line,
+				Message: fmt.Sprintf("badge points at %s, want %s", badge.Repo, slug),
+			})
+		}
+		if i > 0 {
+			findings = append(findings, finding{
+				Code:    findingDuplicate,
+				Line:    badge.Line,
+				Message: fmt.Sprintf("duplicate badge (first on line %d)", badges[0].Line),
+			})
+		}
+	}
+	return findings
+}
+
+// printAnnotation writes a finding as a GitHub Actions workflow command.
+func printAnnotation(w io.Writer, file string, f finding) {
+	props := "file=" + escapeProperty(file)
+	if f.Line > 0 {
+		props += fmt.Sprintf(",line=%d", f.Line)
+	}
+	props += ",title=bfast " + f.Code
+	fmt.Fprintf(w, "::error %s::%s\n", props, escapeData(f.Message))
+}
+
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ",", "%2C", ":", "%3A").Replace(s)
+}
+
+func checkFormat(opts *options) (string, error) {
+	switch opts.format {
+	case "":
+		if opts.json {
+			return formatJSON, nil
+		}
+		return formatText, nil
+	case formatText, formatJSON, formatGithub:
+		return opts.format, nil
+	default:
+		return "", fmt.Errorf("unknown format %q (want text, json, or github)", opts.format)
+	}
+}
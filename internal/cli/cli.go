@@ -7,13 +7,19 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/arrno/bfast/internal/api"
 	"github.com/arrno/bfast/internal/blurb"
+	"github.com/arrno/bfast/internal/diff"
 	"github.com/arrno/bfast/internal/git"
+	"github.com/arrno/bfast/internal/har"
 	"github.com/arrno/bfast/internal/normalize"
 	"github.com/arrno/bfast/internal/readme"
 )
@@ -22,236 +28,519 @@ const apiBaseEnv = "BFAST_API_BASE_URL"
 
 // Run executes the CLI and returns an exit code.
 func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
-	opts, err := parseArgs(args, stderr)
+	cmd := defaultCommand
+	if len(args) > 0 {
+		if args[0] == "help" {
+			return runHelp(args[1:], stdout, stderr)
+		}
+		if sub, ok := lookupCommand(args[0]); ok {
+			cmd = sub
+			args = args[1:]
+		} else if match := suggestCommand(args[0]); match != "" {
+			err := fmt.Errorf("unknown command %q; did you mean %q?", args[0], match)
+			emitError(err, exitUsage, slices.Contains(args, "--json"), stdout, stderr)
+			return exitUsage
+		}
+	}
+
+	return runCommand(ctx, cmd, args, stdout, stderr)
+}
+
+func runCommand(ctx context.Context, cmd *command, args []string, stdout, stderr io.Writer) int {
+	opts, err := parseArgs(cmd, args, stderr)
 	if err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			return 0
+			return exitOK
 		}
-		emitError(err, opts != nil && opts.json, stdout, stderr)
-		return 2
+		emitError(err, exitUsage, opts != nil && opts.json, stdout, stderr)
+		return exitUsage
+	}
+
+	logger, err := newLogger(opts, stderr)
+	if err != nil {
+		emitError(err, exitUsage, opts.json, stdout, stderr)
+		return exitUsage
+	}
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logger)
+	slog.Debug("running command", "command", cmd.path())
+
+	if opts.traceHTTP != "" {
+		if err := startTrace(opts); err != nil {
+			emitError(err, exitFailure, opts.json, stdout, stderr)
+			return exitFailure
+		}
+	}
+
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
 	}
 
-	res, err := execute(ctx, opts, stderr)
+	err = cmd.exec(ctx, opts, stdout, stderr)
+	if opts.transport != nil {
+		if traceErr := writeTrace(opts); traceErr != nil {
+			if err == nil {
+				err = traceErr
+			} else {
+				err = fmt.Errorf("%w (%v)", err, traceErr)
+			}
+		}
+	}
 	if err != nil {
-		emitError(err, opts.json, stdout, stderr)
-		return 1
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = &hintError{msg: fmt.Sprintf("timed out after %s (--timeout): %v", opts.timeout, err), err: err}
+		}
+		code := exitCode(err)
+		var exit *exitError
+		if !errors.As(err, &exit) {
+			emitError(err, code, opts.json, stdout, stderr)
+		}
+		return code
 	}
 
-	emitResult(res, opts.json, stdout)
-	return 0
+	return exitOK
 }
 
-type stringValue struct {
-	value string
-	set   bool
+// exitError carries a non-zero exit code for commands that already reported
+// their outcome and only need the process status to reflect it.
+type exitError struct {
+	code int
 }
 
-func (s *stringValue) Set(v string) error {
-	s.value = v
-	s.set = true
-	return nil
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
 }
 
-func (s *stringValue) String() string { return s.value }
+type options struct {
+	blurb           string
+	blurbProvided   bool
+	repoInput       string
+	readmeInput     string
+	hidden          bool
+	hiddenProvided  bool
+	dryRun          bool
+	forceBadge      bool
+	json            bool
+	verbose         bool
+	logLevel        string
+	logFormat       string
+	dir             string
+	batchDir        string
+	reposFile       string
+	jobs            int
+	placement       string
+	format          string
+	commit          bool
+	commitMessage   string
+	pr              bool
+	prBranch        string
+	prBase          string
+	verify          bool
+	remote          string
+	remotes         []string
+	forkCheck       string
+	forkAPI         bool
+	forkAPIProvided bool
+	forges          normalize.Hosts
+	profile         string
+	api             apiSettings
+	traceHTTP       string
+	withToken       bool
+	retries         int
+	retriesProvided bool
+	timeout         time.Duration
+	caCert          string
+	clientCert      string
+	clientKey       string
+	proxy           string
+	transport       *har.Recorder
+	args            []string
+	sources         map[string]string
+	// warnings are raised while resolving settings, before there is a
+	// result to carry them.
+	warnings []string
+}
 
-func parseArgs(args []string, stderr io.Writer) (*options, error) {
-	fs := flag.NewFlagSet("bfast", flag.ContinueOnError)
-	fs.SetOutput(stderr)
+type result struct {
+	Repo               string            `json:"repo"`
+	RepoURL            string            `json:"repoUrl"`
+	Remote             string            `json:"remote,omitempty"`
+	ForkOf             string            `json:"forkOf,omitempty"`
+	Readme             string            `json:"readme"`
+	Blurb              string            `json:"blurb"`
+	Hidden             bool              `json:"hidden"`
+	Registered         bool              `json:"registered"`
+	Verified           bool              `json:"verified"`
+	VerificationFile   string            `json:"verificationFile,omitempty"`
+	AlreadyRegistered  bool              `json:"alreadyRegistered"`
+	BadgeInserted      bool              `json:"badgeInserted"`
+	AlreadyBadged      bool              `json:"alreadyBadged"`
+	DryRun             bool              `json:"dryRun"`
+	BadgeMarkdown      string            `json:"badge"`
+	BadgeImageURL      string            `json:"badgeImage"`
+	BadgeDestination   string            `json:"badgeLink"`
+	RegistrationFailed string            `json:"registrationError,omitempty"`
+	ServerProject      *api.Project      `json:"server,omitempty"`
+	Mismatch           []string          `json:"mismatch,omitempty"`
+	Sources            map[string]string `json:"sources,omitempty"`
+	Diff               string            `json:"diff,omitempty"`
+	Commit             string            `json:"commit,omitempty"`
+	Branch             string            `json:"branch,omitempty"`
+	PullRequest        string            `json:"pullRequest,omitempty"`
+	Attempts           int               `json:"attempts,omitempty"`
+	Notices            []string          `json:"notices,omitempty"`
+	Warnings           []string          `json:"warnings,omitempty"`
+}
 
-	var blurbValue stringValue
-	fs.Var(&blurbValue, "blurb", "Custom blurb text (max 128 chars)")
-	fs.Var(&blurbValue, "m", "Custom blurb text (shorthand)")
+// notice records an informational message. Text output prints it to
+// stderr; --json output carries it in the result.
+func (r *result) notice(format string, args ...interface{}) {
+	r.Notices = append(r.Notices, fmt.Sprintf(format, args...))
+}
 
-	repo := fs.String("repo", "", "Target repository (owner/repo or GitHub URL)")
-	readmePath := fs.String("readme", "", "Path to README (defaults to repo README)")
-	hidden := fs.Bool("hidden", false, "Submit as hidden")
-	dryRun := fs.Bool("dry-run", false, "Show actions without making changes")
-	forceBadge := fs.Bool("force-badge", false, "Insert badge even if API call fails")
-	jsonOut := fs.Bool("json", false, "Emit machine-readable JSON output")
+// warn records a problem that did not stop the run.
+func (r *result) warn(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
 
-	if err := fs.Parse(args); err != nil {
-		return &options{json: jsonOut != nil && *jsonOut}, err
+func runDefault(ctx context.Context, opts *options, stdout, stderr io.Writer) error {
+	res, err := execute(ctx, opts)
+	if err != nil {
+		return err
 	}
 
-	remainingArgs := fs.Args()
-	if len(remainingArgs) > 1 {
-		return &options{json: jsonOut != nil && *jsonOut}, errors.New("too many positional arguments")
+	emitResult(res, opts.json, stdout, stderr)
+	return nil
+}
+
+// execute runs the register-and-badge pipeline. Notices and warnings are
+// recorded in the result rather than printed, so callers decide where they
+// go.
+func execute(ctx context.Context, opts *options) (*result, error) {
+	tgt, err := resolveTarget(ctx, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	positionalRepo := ""
-	if len(remainingArgs) == 1 {
-		positionalRepo = remainingArgs[0]
+	file, err := loadReadme(tgt, opts.readmeInput)
+	if err != nil {
+		return nil, err
 	}
 
-	if *repo != "" && positionalRepo != "" {
-		return &options{json: jsonOut != nil && *jsonOut}, errors.New("repo provided via --repo and positional argument")
+	res := newResult(tgt, file, opts)
+	if err := checkFork(ctx, opts, tgt, res); err != nil {
+		return nil, err
 	}
 
-	targetRepo := *repo
-	if targetRepo == "" {
-		targetRepo = positionalRepo
+	if readme.HasBadge(file.content) {
+		res.AlreadyBadged = true
+		return res, nil
 	}
 
-	return &options{
-		blurb:         blurbValue.value,
-		blurbProvided: blurbValue.set,
-		repoInput:     strings.TrimSpace(targetRepo),
-		readmeInput:   strings.TrimSpace(*readmePath),
-		hidden:        *hidden,
-		dryRun:        *dryRun,
-		forceBadge:    *forceBadge,
-		json:          *jsonOut,
-	}, nil
-}
+	if err := chooseBlurb(opts, res); err != nil {
+		return nil, err
+	}
 
-type options struct {
-	blurb         string
-	blurbProvided bool
-	repoInput     string
-	readmeInput   string
-	hidden        bool
-	dryRun        bool
-	forceBadge    bool
-	json          bool
-}
+	badge := readme.BuildBadgeMarkdown(tgt.slug.Encoded())
+	res.BadgeMarkdown = badge
 
-type result struct {
-	Repo               string `json:"repo"`
-	RepoURL            string `json:"repoUrl"`
-	Readme             string `json:"readme"`
-	Blurb              string `json:"blurb"`
-	Hidden             bool   `json:"hidden"`
-	Registered         bool   `json:"registered"`
-	AlreadyRegistered  bool   `json:"alreadyRegistered"`
-	BadgeInserted      bool   `json:"badgeInserted"`
-	AlreadyBadged      bool   `json:"alreadyBadged"`
-	DryRun             bool   `json:"dryRun"`
-	BadgeMarkdown      string `json:"badge"`
-	BadgeImageURL      string `json:"badgeImage"`
-	BadgeDestination   string `json:"badgeLink"`
-	RegistrationFailed string `json:"registrationError,omitempty"`
-}
-
-func execute(ctx context.Context, opts *options, stderr io.Writer) (*result, error) {
-	cwd, err := os.Getwd()
+	if opts.dryRun {
+		updated, err := readme.InsertBadgeAt(file.content, badge, readme.Placement(opts.placement))
+		if err != nil {
+			return nil, err
+		}
+		res.Diff = readmeDiff(tgt, file, updated)
+		return res, nil
+	}
+
+	pr, err := prepareInsert(ctx, opts, tgt)
 	if err != nil {
 		return nil, err
 	}
 
-	root, rootErr := git.FindRepoRoot(cwd)
-	if rootErr != nil {
-		if !errors.Is(rootErr, git.ErrNotRepository) {
-			return nil, rootErr
+	if err := checkVerify(ctx, opts, tgt); err != nil {
+		return nil, err
+	}
+
+	client := newAPIClient(opts)
+
+	challenge, err := verifyOwnership(ctx, client, opts, tgt, res)
+	if err == nil {
+		err = registerRepo(ctx, client, tgt.slug, challenge, opts, res)
+	}
+	if err != nil {
+		if !opts.forceBadge {
+			return nil, err
 		}
-		root = ""
+		res.RegistrationFailed = err.Error()
+		res.warn("registration failed (%s). Continuing due to --force-badge.", err)
+	}
+
+	if err := insertBadge(ctx, opts, tgt, file, badge, pr, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// target captures the working directory, git root, and repository a command
+// operates on.
+type target struct {
+	cwd  string
+	root string
+	slug normalize.Slug
+	// detection is how slug was picked from the remotes; nil when it came
+	// from --repo.
+	detection *git.Detection
+}
+
+func resolveTarget(ctx context.Context, opts *options) (*target, error) {
+	tgt, err := locateRepo(opts)
+	if err != nil {
+		return nil, err
 	}
 
-	var slug normalize.Slug
 	switch {
 	case opts.repoInput != "":
-		slug, err = normalize.Parse(opts.repoInput)
+		tgt.slug, err = opts.forges.Parse(opts.repoInput)
 		if err != nil {
 			return nil, err
 		}
 	default:
-		if root == "" {
+		if tgt.root == "" {
 			return nil, git.ErrNotRepository
 		}
-		slug, err = git.DetectGithubSlug(ctx, root)
+		tgt.detection, err = git.DetectRepo(ctx, tgt.root, git.RemoteOptions{Name: opts.remote, Preference: opts.remotes, Hosts: opts.forges})
 		if err != nil {
 			return nil, err
 		}
+		tgt.slug = tgt.detection.Slug
 	}
 
-	readmePath, err := resolveReadmePath(root, cwd, opts.readmeInput)
+	return tgt, nil
+}
+
+// locateRepo finds the working directory and enclosing git root without
+// inferring a slug, then layers environment and repo config settings into
+// opts. An empty opts.dir means the process working directory. The root is
+// empty outside a repository.
+func locateRepo(opts *options) (*target, error) {
+	cwd, err := workingDir(opts.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := git.FindRepoRoot(cwd)
+	if err != nil {
+		if !errors.Is(err, git.ErrNotRepository) {
+			return nil, err
+		}
+		root = ""
+	}
+
+	if err := applySettings(opts, root); err != nil {
+		return nil, err
+	}
+
+	return &target{cwd: cwd, root: root}, nil
+}
+
+func workingDir(dir string) (string, error) {
+	if dir == "" {
+		return os.Getwd()
+	}
+	return filepath.Abs(dir)
+}
+
+// readmeFile holds the README contents along with the file mode used when
+// writing it back.
+type readmeFile struct {
+	path    string
+	content string
+	mode    os.FileMode
+}
+
+func loadReadme(tgt *target, override string) (*readmeFile, error) {
+	readmePath, err := resolveReadmePath(tgt.root, tgt.cwd, override)
+	if errors.Is(err, readme.ErrNotFound) {
+		return nil, &fileError{path: tgt.root, err: err}
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	info, err := os.Stat(readmePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, &fileError{path: readmePath, err: fmt.Errorf("%w: %s", readme.ErrNotFound, readmePath)}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("unable to access README: %w", err)
+		return nil, &fileError{path: readmePath, err: fmt.Errorf("unable to access README: %w", err)}
 	}
 	if info.IsDir() {
-		return nil, fmt.Errorf("%s is a directory", readmePath)
+		return nil, &fileError{path: readmePath, err: fmt.Errorf("%s is a directory", readmePath)}
 	}
 
 	rawContent, err := os.ReadFile(readmePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read README: %w", err)
+		return nil, &fileError{path: readmePath, err: fmt.Errorf("failed to read README: %w", err)}
 	}
 
-	content := string(rawContent)
-	res := &result{
-		Repo:             slug.String(),
-		RepoURL:          slug.RepoURL(),
-		Readme:           readmePath,
-		Hidden:           opts.hidden,
-		DryRun:           opts.dryRun,
-		BadgeImageURL:    readme.BadgeImageURL,
-		BadgeDestination: readme.BadgeLinkURL,
-	}
+	return &readmeFile{path: readmePath, content: string(rawContent), mode: info.Mode()}, nil
+}
 
-	if readme.HasBadge(content) {
-		res.AlreadyBadged = true
-		return res, nil
+// prepareInsert checks the --commit and --pr preconditions before anything
+// is written or registered. The pull request is nil without --pr.
+func prepareInsert(ctx context.Context, opts *options, tgt *target) (*pullRequest, error) {
+	if err := checkCommit(ctx, opts, tgt); err != nil {
+		return nil, err
 	}
+	return preparePR(ctx, opts, tgt)
+}
 
-	var blurbText string
-	if opts.blurbProvided {
-		blurbText, err = blurb.Normalize(opts.blurb)
-		if err != nil {
-			return nil, err
+// insertBadge writes the badge into the README and commits it with --commit.
+// With a pull request, the commit goes on its own branch, which is pushed
+// and proposed; on failure the checkout returns to the starting branch.
+func insertBadge(ctx context.Context, opts *options, tgt *target, file *readmeFile, badge string, pr *pullRequest, res *result) error {
+	if pr != nil {
+		if err := pr.branchOff(ctx, tgt); err != nil {
+			return err
+		}
+	}
+	if err := writeBadge(file, badge, opts.placement); err != nil {
+		return pr.abandon(ctx, tgt, res, err)
+	}
+	res.BadgeInserted = true
+	if err := commitReadme(ctx, opts, tgt, file, res); err != nil {
+		return pr.abandon(ctx, tgt, res, err)
+	}
+	if pr != nil {
+		if err := pr.open(ctx, tgt, res); err != nil {
+			return pr.abandon(ctx, tgt, res, err)
 		}
-	} else {
-		blurbText = blurb.Random()
-		fmt.Fprintf(stderr, "No blurb provided.\nUsing default speed claim: \"%s\".\n", blurbText)
 	}
-	res.Blurb = blurbText
+	return nil
+}
 
-	badge := readme.BuildBadgeMarkdown(slug.Encoded())
-	res.BadgeMarkdown = badge
+func writeBadge(file *readmeFile, badge, placement string) error {
+	updated, err := readme.InsertBadgeAt(file.content, badge, readme.Placement(placement))
+	if err != nil {
+		return err
+	}
 
-	if opts.dryRun {
-		return res, nil
+	return file.write(updated)
+}
+
+func (f *readmeFile) write(content string) error {
+	if err := os.WriteFile(f.path, []byte(content), f.mode); err != nil {
+		return &fileError{path: f.path, err: fmt.Errorf("%w: %w", errReadmeWrite, err)}
 	}
 
-	apiBase := strings.TrimSpace(os.Getenv(apiBaseEnv))
-	client := api.NewClient(apiBase, nil)
+	f.content = content
+	return nil
+}
 
-	if err := registerRepo(ctx, client, slug, opts, res); err != nil {
-		if !opts.forceBadge {
-			return nil, err
+// readmeDiff renders the change to the README as a unified diff, labelled
+// with its path relative to the git root when possible.
+func readmeDiff(tgt *target, file *readmeFile, updated string) string {
+	name := filepath.Base(file.path)
+	if tgt.root != "" {
+		if rel, err := filepath.Rel(tgt.root, file.path); err == nil && !strings.HasPrefix(rel, "..") {
+			name = filepath.ToSlash(rel)
 		}
-		res.RegistrationFailed = err.Error()
-		fmt.Fprintf(stderr, "Warning: registration failed (%s). Continuing due to --force-badge.\n", err)
 	}
+	return diff.Unified("a/"+name, "b/"+name, file.content, updated)
+}
 
-	updated, err := readme.InsertBadge(content, badge)
-	if err != nil {
-		return nil, err
+// printDiff writes a diff, colored when w is a terminal and NO_COLOR is unset.
+func printDiff(w io.Writer, d string) {
+	if d == "" {
+		return
 	}
+	if isTerminal(w) && os.Getenv("NO_COLOR") == "" {
+		d = diff.Colorize(d)
+	}
+	fmt.Fprint(w, d)
+}
 
-	if err := os.WriteFile(readmePath, []byte(updated), info.Mode()); err != nil {
-		return nil, fmt.Errorf("failed to update README: %w", err)
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
 	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
 
-	res.BadgeInserted = true
+func newResult(tgt *target, file *readmeFile, opts *options) *result {
+	res := &result{
+		Repo:             tgt.slug.String(),
+		RepoURL:          tgt.slug.RepoURL(),
+		Hidden:           opts.hidden,
+		DryRun:           opts.dryRun,
+		BadgeImageURL:    readme.BadgeImageURL,
+		BadgeDestination: readme.BadgeLinkURL,
+		Sources:          opts.sources,
+		Warnings:         append([]string(nil), opts.warnings...),
+	}
+	if file != nil {
+		res.Readme = file.path
+	}
+	if tgt.detection != nil {
+		res.Remote = tgt.detection.Remote
+	}
+	return res
+}
 
-	return res, nil
+// chooseBlurb sets res.Blurb to the normalized blurb, or to a random default
+// with a notice when none was given.
+func chooseBlurb(opts *options, res *result) error {
+	if opts.blurbProvided {
+		text, err := blurb.Normalize(opts.blurb)
+		if err != nil {
+			return err
+		}
+		res.Blurb = text
+		return nil
+	}
+
+	res.Blurb = blurb.Random()
+	res.notice("No blurb provided. Using default speed claim: \"%s\".", res.Blurb)
+	return nil
+}
+
+func newAPIClient(opts *options) *api.Client {
+	cfg := api.Config{
+		BaseURL:   opts.api.baseURL,
+		Token:     opts.api.token,
+		Timeout:   opts.api.timeout,
+		Transport: opts.roundTripper(),
+	}
+	if opts.api.retries >= 0 {
+		cfg.Retry.MaxAttempts = opts.api.retries + 1
+	}
+	return api.New(cfg)
 }
 
-func registerRepo(ctx context.Context, client *api.Client, slug normalize.Slug, opts *options, res *result) error {
+// registerRepo submits the repo, along with the verified challenge token when
+// there is one.
+func registerRepo(ctx context.Context, client *api.Client, slug normalize.Slug, challenge string, opts *options, res *result) error {
 	submission := api.Submission{
 		RepoURL:         slug.RepoURL(),
 		IsBlazinglyFast: true,
 		Blurb:           res.Blurb,
 		Hidden:          opts.hidden,
+		Challenge:       challenge,
 	}
 
-	if _, err := client.Submit(ctx, submission); err != nil {
+	before := client.Attempts()
+	_, err := client.Submit(ctx, submission)
+	res.Attempts = client.Attempts() - before
+	if err != nil {
 		if errors.Is(err, api.ErrAlreadyRegistered) {
 			res.AlreadyRegistered = true
+			compareRegistration(ctx, client, opts, res)
 			return nil
 		}
 		return err
@@ -261,6 +550,40 @@ func registerRepo(ctx context.Context, client *api.Client, slug normalize.Slug,
 	return nil
 }
 
+// compareRegistration records which explicitly requested values differ from
+// what the server already has. Lookup failures are ignored; the comparison is
+// advisory and must not fail an otherwise successful run.
+func compareRegistration(ctx context.Context, client *api.Client, opts *options, res *result) {
+	project, err := client.Lookup(ctx, res.RepoURL)
+	if err != nil {
+		return
+	}
+
+	res.ServerProject = project
+	if opts.blurbProvided && project.Blurb != res.Blurb {
+		res.Mismatch = append(res.Mismatch, "blurb")
+	}
+	if opts.hiddenProvided && project.Hidden != opts.hidden {
+		res.Mismatch = append(res.Mismatch, "hidden")
+	}
+}
+
+func printMismatch(stdout io.Writer, res *result) {
+	if len(res.Mismatch) == 0 {
+		return
+	}
+
+	for _, field := range res.Mismatch {
+		switch field {
+		case "blurb":
+			fmt.Fprintf(stdout, "Server blurb is \"%s\", not \"%s\".\n", res.ServerProject.Blurb, res.Blurb)
+		case "hidden":
+			fmt.Fprintf(stdout, "Server hidden flag is %t, not %t.\n", res.ServerProject.Hidden, res.Hidden)
+		}
+	}
+	fmt.Fprintln(stdout, "Run \"bfast update\" to change the existing registration.")
+}
+
 func resolveReadmePath(root, cwd, override string) (string, error) {
 	if override != "" {
 		if filepath.IsAbs(override) {
@@ -274,7 +597,7 @@ func resolveReadmePath(root, cwd, override string) (string, error) {
 	}
 
 	if root == "" {
-		return "", fmt.Errorf("cannot locate README outside a git repo; pass --readme")
+		return "", fmt.Errorf("%w: cannot locate README outside a git repo; pass --readme", readme.ErrNotFound)
 	}
 
 	return readme.FindDefault(root)
@@ -290,28 +613,66 @@ func printSummary(stdout io.Writer, res *result) {
 		fmt.Fprintf(stdout, "Registered %s with blurb: \"%s\"\n", res.Repo, res.Blurb)
 	}
 	fmt.Fprintf(stdout, "Badge added to %s\n", res.Readme)
+	printVerification(stdout, res)
+	printCommit(stdout, res)
+	printPullRequest(stdout, res)
+	printMismatch(stdout, res)
+}
+
+func printCommit(stdout io.Writer, res *result) {
+	if res.Commit == "" {
+		return
+	}
+	short := res.Commit
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	fmt.Fprintf(stdout, "Committed as %s\n", short)
 }
 
-func emitResult(res *result, jsonOut bool, stdout io.Writer) {
+func printPullRequest(stdout io.Writer, res *result) {
+	if res.PullRequest == "" {
+		return
+	}
+	fmt.Fprintf(stdout, "Opened pull request from %s: %s\n", res.Branch, res.PullRequest)
+}
+
+func emitResult(res *result, jsonOut bool, stdout, stderr io.Writer) {
 	if jsonOut {
-		_ = json.NewEncoder(stdout).Encode(res)
+		emitJSON(res, stdout)
 		return
 	}
 
+	printDiagnostics(stderr, res)
+
 	switch {
 	case res.AlreadyBadged:
 		fmt.Fprintln(stdout, "Already badged. No changes.")
 	case res.DryRun:
 		fmt.Fprintf(stdout, "Dry run: would register %s and update %s\n", res.Repo, res.Readme)
+		printDiff(stdout, res.Diff)
 	default:
 		printSummary(stdout, res)
 	}
 }
 
-func emitError(err error, jsonOut bool, stdout, stderr io.Writer) {
+// printDiagnostics writes the notices and warnings of a text-mode run.
+func printDiagnostics(stderr io.Writer, res *result) {
+	for _, msg := range res.Notices {
+		fmt.Fprintln(stderr, msg)
+	}
+	for _, msg := range res.Warnings {
+		fmt.Fprintf(stderr, "Warning: %s\n", msg)
+	}
+}
+
+func emitJSON(v interface{}, stdout io.Writer) {
+	_ = json.NewEncoder(stdout).Encode(v)
+}
+
+func emitError(err error, code int, jsonOut bool, stdout, stderr io.Writer) {
 	if jsonOut {
-		payload := map[string]string{"error": err.Error()}
-		_ = json.NewEncoder(stdout).Encode(payload)
+		emitJSON(describeError(err, code), stdout)
 		return
 	}
 
@@ -2,16 +2,24 @@ package cli
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/arrno/bfast/internal/config"
+	"github.com/arrno/bfast/internal/har"
 	"github.com/arrno/bfast/internal/readme"
 )
 
@@ -20,6 +28,7 @@ type submission struct {
 	IsBlazinglyFast bool   `json:"isBlazinglyFast"`
 	Blurb           string `json:"blurb"`
 	Hidden          bool   `json:"hidden"`
+	Challenge       string `json:"challenge"`
 }
 
 func TestIntegrationRegistersAndBadges(t *testing.T) {
@@ -245,3 +254,1822 @@ func runGit(t *testing.T, dir string, args ...string) {
 		t.Fatalf("git %v: %v", args, err)
 	}
 }
+
+func TestIntegrationBadgeCommandSkipsAPI(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+	readmePath := filepath.Join(temp, "README.md")
+	if err := os.WriteFile(readmePath, []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("API should not be called by badge")
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"badge"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+
+	content, err := os.ReadFile(readmePath)
+	if err != nil {
+		t.Fatalf("read README: %v", err)
+	}
+	if !strings.Contains(string(content), "repo=arrno%2Fdemo") {
+		t.Fatalf("badge not inserted: %s", string(content))
+	}
+}
+
+func TestIntegrationRegisterCommandLeavesReadme(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+	readmePath := filepath.Join(temp, "README.md")
+	if err := os.WriteFile(readmePath, []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+
+	var sub submission
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"register", "-m", "Only the API", "--hidden"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+
+	if sub.Blurb != "Only the API" || !sub.Hidden {
+		t.Fatalf("unexpected submission: %+v", sub)
+	}
+
+	content, err := os.ReadFile(readmePath)
+	if err != nil {
+		t.Fatalf("read README: %v", err)
+	}
+	if readme.HasBadge(string(content)) {
+		t.Fatalf("register should not touch the README")
+	}
+}
+
+func TestIntegrationRemoveCommandStripsBadge(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+
+	badge := readme.BuildBadgeMarkdown("arrno%2Fdemo")
+	readmePath := filepath.Join(temp, "README.md")
+	if err := os.WriteFile(readmePath, []byte("# Demo\n"+badge+"\n\nHi\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"remove", "--json"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+
+	var res removeResult
+	if err := json.Unmarshal([]byte(stdout.String()), &res); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if res.Removed != 1 {
+		t.Fatalf("removed = %d, want 1", res.Removed)
+	}
+
+	content, err := os.ReadFile(readmePath)
+	if err != nil {
+		t.Fatalf("read README: %v", err)
+	}
+	if string(content) != "# Demo\n\nHi\n" {
+		t.Fatalf("unexpected README after remove: %q", string(content))
+	}
+}
+
+func TestIntegrationStatusCombinesReadmeAndAPI(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "git@github.com:arrno/demo.git")
+	readmePath := filepath.Join(temp, "README.md")
+	original := "# Demo\n"
+	if err := os.WriteFile(readmePath, []byte(original), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Fatalf("status must not write, got %s", r.Method)
+		}
+		if got := r.URL.Query().Get("repoUrl"); got != "https://github.com/arrno/demo" {
+			t.Fatalf("unexpected repoUrl query: %q", got)
+		}
+		_, _ = w.Write([]byte(`{"project":{"id":"7","repoUrl":"https://github.com/arrno/demo","blurb":"Quick","hidden":true}}`))
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"status", "--json"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+
+	var res statusResult
+	if err := json.Unmarshal([]byte(stdout.String()), &res); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if !res.Registered || !res.Hidden || res.Blurb != "Quick" || res.Badged {
+		t.Fatalf("unexpected status: %+v", res)
+	}
+
+	content, err := os.ReadFile(readmePath)
+	if err != nil {
+		t.Fatalf("read README: %v", err)
+	}
+	if string(content) != original {
+		t.Fatalf("status modified README: %q", string(content))
+	}
+}
+
+func TestIntegrationStatusReportsUnregistered(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"status"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "README:     not found") || !strings.Contains(stdout.String(), "Registered: no") {
+		t.Fatalf("unexpected status output: %q", stdout.String())
+	}
+
+	// Outside a checkout there is no README to find, but the API is still asked.
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	stdout.Reset()
+	stderr.Reset()
+	code = Run(context.Background(), []string{"status", "arrno/demo"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run outside a checkout exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "README:     not found") || !strings.Contains(stdout.String(), "Registered: no") {
+		t.Fatalf("unexpected status output outside a checkout: %q", stdout.String())
+	}
+}
+
+func TestIntegrationUpdateSendsOnlyProvidedFields(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Fatalf("unexpected method: %s", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"project":{"repoUrl":"https://github.com/arrno/demo","blurb":"Old news","hidden":false}}`))
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+	// Settings from the environment or config files are not changes to send.
+	t.Setenv("BFAST_BLURB", "From env")
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"update", "--repo", "arrno/demo", "--hidden=false"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+
+	if _, ok := body["blurb"]; ok {
+		t.Fatalf("blurb should not be sent when not provided: %v", body)
+	}
+	if hidden, ok := body["hidden"]; !ok || hidden != false {
+		t.Fatalf("hidden=false should be sent: %v", body)
+	}
+	if !strings.Contains(stdout.String(), "Hidden: false") {
+		t.Fatalf("unexpected output: %q", stdout.String())
+	}
+}
+
+func TestIntegrationUpdateRequiresAField(t *testing.T) {
+	t.Setenv("BFAST_HIDDEN", "true")
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"update", "--repo", "arrno/demo"}, stdout, stderr)
+	if code == 0 {
+		t.Fatalf("expected failure without -m or --hidden")
+	}
+	if !strings.Contains(stderr.String(), "nothing to update") {
+		t.Fatalf("unexpected stderr: %q", stderr.String())
+	}
+}
+
+func TestIntegrationAlreadyRegisteredReportsMismatch(t *testing.T) {
+	temp := t.TempDir()
+	readmePath := filepath.Join(temp, "README.md")
+	if err := os.WriteFile(readmePath, []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(http.StatusConflict)
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`{"project":{"repoUrl":"https://github.com/arrno/demo","blurb":"Old news","hidden":false}}`))
+		default:
+			t.Fatalf("unexpected method: %s", r.Method)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	args := []string{"--repo", "arrno/demo", "--readme", readmePath, "-m", "New news", "--hidden", "--json"}
+	code := Run(context.Background(), args, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+
+	var res result
+	if err := json.Unmarshal([]byte(stdout.String()), &res); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if !res.AlreadyRegistered || !res.BadgeInserted {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if strings.Join(res.Mismatch, ",") != "blurb,hidden" {
+		t.Fatalf("mismatch = %v, want [blurb hidden]", res.Mismatch)
+	}
+	if res.ServerProject == nil || res.ServerProject.Blurb != "Old news" {
+		t.Fatalf("server project not reported: %+v", res.ServerProject)
+	}
+}
+
+func TestIntegrationBatchContinuesPastFailures(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"alpha", "beta", "broken"} {
+		dir := filepath.Join(root, name)
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		initGitRepo(t, dir, "https://github.com/arrno/"+name+".git")
+		if name == "broken" {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# "+name+"\n"), 0o644); err != nil {
+			t.Fatalf("write README: %v", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, "not-a-repo"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	var mu sync.Mutex
+	registered := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var sub submission
+		if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		mu.Lock()
+		registered[sub.RepoURL] = true
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"batch", "--jobs", "2", "-m", "Batched", "--json", root}, stdout, stderr)
+	if code != 1 {
+		t.Fatalf("run exit = %d, want 1; stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+
+	var entries []batchEntry
+	if err := json.Unmarshal([]byte(stdout.String()), &entries); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d: %+v", len(entries), entries)
+	}
+
+	for _, entry := range entries {
+		broken := filepath.Base(entry.Path) == "broken"
+		if broken != (entry.Error != "") {
+			t.Fatalf("unexpected entry: %+v", entry)
+		}
+		if !broken && !entry.Result.BadgeInserted {
+			t.Fatalf("badge not inserted for %s", entry.Path)
+		}
+	}
+
+	if !registered["https://github.com/arrno/alpha"] || !registered["https://github.com/arrno/beta"] {
+		t.Fatalf("unexpected registrations: %v", registered)
+	}
+}
+
+func TestIntegrationBatchTextShowsDiagnostics(t *testing.T) {
+	root := t.TempDir()
+	fork := filepath.Join(root, "fork")
+	if err := os.Mkdir(fork, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	initGitRepo(t, fork, "https://github.com/someone/demo.git")
+	runGit(t, fork, "remote", "add", "upstream", "https://github.com/arrno/demo.git")
+	if err := os.WriteFile(filepath.Join(fork, "README.md"), []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	if code := Run(context.Background(), []string{"batch", "--dry-run", root}, stdout, stderr); code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stderr.String(), fork+": Warning: ") || !strings.Contains(stderr.String(), "arrno/demo") {
+		t.Fatalf("fork warning missing from stderr: %q", stderr.String())
+	}
+	if !strings.Contains(stderr.String(), fork+": No blurb") {
+		t.Fatalf("default blurb notice missing from stderr: %q", stderr.String())
+	}
+}
+
+func TestIntegrationRepoConfigLayering(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+
+	docs := filepath.Join(temp, "docs")
+	if err := os.Mkdir(docs, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	readmePath := filepath.Join(docs, "README.md")
+	if err := os.WriteFile(readmePath, []byte("[![ci](ci)](ci)\n\n# Demo\nHi\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+
+	cfg := "blurb = \"From config\"\nhidden = true\nreadme = \"docs/README.md\"\nplacement = \"bottom\"\n"
+	if err := os.WriteFile(filepath.Join(temp, ".bfast.toml"), []byte(cfg), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var sub submission
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+	t.Setenv("BFAST_HIDDEN", "false")
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(docs); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"--json"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+
+	if sub.Blurb != "From config" || sub.Hidden {
+		t.Fatalf("unexpected submission: %+v", sub)
+	}
+
+	var res result
+	if err := json.Unmarshal([]byte(stdout.String()), &res); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	cfgPath := filepath.Join(temp, ".bfast.toml")
+	wantSources := map[string]string{"blurb": cfgPath, "hidden": "env", "readme": cfgPath, "placement": cfgPath}
+	for key, want := range wantSources {
+		if res.Sources[key] != want {
+			t.Fatalf("source of %s = %q, want %q (all: %v)", key, res.Sources[key], want, res.Sources)
+		}
+	}
+
+	content, err := os.ReadFile(readmePath)
+	if err != nil {
+		t.Fatalf("read README: %v", err)
+	}
+	if !strings.HasSuffix(string(content), "Hi\n\n"+readme.BuildBadgeMarkdown("arrno%2Fdemo")+"\n") {
+		t.Fatalf("badge not placed at bottom: %q", string(content))
+	}
+}
+
+func TestIntegrationRepoConfigReadmeStaysInRepo(t *testing.T) {
+	base := t.TempDir()
+	temp := filepath.Join(base, "repo")
+	if err := os.Mkdir(temp, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+	if err := os.WriteFile(filepath.Join(temp, "README.md"), []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	victim := filepath.Join(base, "victim.md")
+	if err := os.WriteFile(victim, []byte("# Victim\n"), 0o644); err != nil {
+		t.Fatalf("write victim: %v", err)
+	}
+	if err := os.Symlink(victim, filepath.Join(temp, "link.md")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	for _, value := range []string{"../victim.md", victim, "docs/../../victim.md", "link.md"} {
+		cfg := fmt.Sprintf("readme = %q\n", value)
+		if err := os.WriteFile(filepath.Join(temp, ".bfast.toml"), []byte(cfg), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+
+		stderr := &strings.Builder{}
+		code := Run(context.Background(), []string{"badge"}, io.Discard, stderr)
+		if code == 0 || !strings.Contains(stderr.String(), "inside the repository") {
+			t.Fatalf("readme %q: exit = %d, stderr=%q", value, code, stderr.String())
+		}
+		if content, _ := os.ReadFile(victim); string(content) != "# Victim\n" {
+			t.Fatalf("readme %q: file outside the repo was written: %q", value, content)
+		}
+	}
+
+	// An explicit --readme is the user's own choice and may leave the repo.
+	if code := Run(context.Background(), []string{"badge", "--readme", victim}, io.Discard, io.Discard); code != 0 {
+		t.Fatalf("--readme outside the repo: exit = %d", code)
+	}
+}
+
+func TestIntegrationFlagsOverrideRepoConfig(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+	if err := os.WriteFile(filepath.Join(temp, "README.md"), []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(temp, ".bfast.json"), []byte(`{"blurb": "From config", "repo": "other/repo"}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"--dry-run", "--json", "-m", "From flag"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+
+	var res result
+	if err := json.Unmarshal([]byte(stdout.String()), &res); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if res.Blurb != "From flag" || res.Sources["blurb"] != sourceFlag {
+		t.Fatalf("flag should win over config: %+v", res)
+	}
+	if res.Repo != "other/repo" {
+		t.Fatalf("repo override from config not applied: %s", res.Repo)
+	}
+
+	// --remote picks the target too, so it beats a repo from env or config.
+	runGit(t, temp, "remote", "add", "upstream", "https://github.com/arrno/upstream.git")
+	t.Setenv("BFAST_REPO", "env/repo")
+	stdout.Reset()
+	stderr.Reset()
+	code = Run(context.Background(), []string{"--dry-run", "--json", "--remote", "upstream"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+	res = result{}
+	if err := json.Unmarshal([]byte(stdout.String()), &res); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if res.Repo != "arrno/upstream" || res.Remote != "upstream" || res.Sources["repo"] != "" {
+		t.Fatalf("--remote should win over a configured repo: %+v", res)
+	}
+}
+
+func TestIntegrationProfileSelectsAPI(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	for _, args := range [][]string{
+		{"config", "set", "profile.staging.base_url", srv.URL},
+		{"config", "set", "profile.staging.token", "s3cret"},
+		{"config", "set", "profile.prod.base_url", "http://127.0.0.1:1"},
+		{"config", "set", "profile", "prod"},
+	} {
+		if code := Run(context.Background(), args, io.Discard, io.Discard); code != 0 {
+			t.Fatalf("%v exit = %d", args, code)
+		}
+	}
+
+	stdout := &strings.Builder{}
+	if code := Run(context.Background(), []string{"config", "list"}, stdout, io.Discard); code != 0 {
+		t.Fatalf("config list exit = %d", code)
+	}
+	if strings.Contains(stdout.String(), "s3cret") || !strings.Contains(stdout.String(), "profile.staging.token = ********") {
+		t.Fatalf("config list should redact tokens: %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := Run(context.Background(), []string{"config", "get", "profile.staging.base_url"}, stdout, io.Discard); code != 0 {
+		t.Fatalf("config get exit = %d", code)
+	}
+	if strings.TrimSpace(stdout.String()) != srv.URL {
+		t.Fatalf("config get = %q, want %q", stdout.String(), srv.URL)
+	}
+
+	// --profile is a flag, so its base_url beats the environment.
+	t.Setenv(apiBaseEnv, "http://127.0.0.1:1")
+	stdout.Reset()
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"register", "--repo", "arrno/demo", "-m", "Staged", "--profile", "staging", "--json"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+	if auth != "Bearer s3cret" {
+		t.Fatalf("Authorization = %q, want profile token", auth)
+	}
+
+	var res result
+	if err := json.Unmarshal([]byte(stdout.String()), &res); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if res.Sources["profile"] != sourceFlag || res.Sources["api"] != "profile staging" {
+		t.Fatalf("unexpected sources: %v", res.Sources)
+	}
+
+	code = Run(context.Background(), []string{"register", "--repo", "arrno/demo", "--profile", "missing"}, io.Discard, stderr)
+	if code == 0 || !strings.Contains(stderr.String(), `unknown profile "missing"`) {
+		t.Fatalf("expected unknown profile error, exit=%d stderr=%q", code, stderr.String())
+	}
+}
+
+func TestIntegrationCommitFlagCommitsReadme(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+	configureGitUser(t, temp)
+
+	readmePath := filepath.Join(temp, "README.md")
+	if err := os.WriteFile(readmePath, []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	args := []string{"-m", "Committed", "--commit-message", "Badge {repo} in {readme}", "--json"}
+	code := Run(context.Background(), args, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+
+	var res result
+	if err := json.Unmarshal([]byte(stdout.String()), &res); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if res.Commit == "" {
+		t.Fatalf("commit hash missing: %+v", res)
+	}
+
+	out, err := exec.Command("git", "-C", temp, "log", "-1", "--format=%s", "--name-only").Output()
+	if err != nil {
+		t.Fatalf("git log: %v", err)
+	}
+	if string(out) != "Badge arrno/demo in README.md\n\nREADME.md\n" {
+		t.Fatalf("unexpected commit: %q", string(out))
+	}
+}
+
+func TestIntegrationCommitRefusesDirtyIndex(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+	configureGitUser(t, temp)
+
+	readmePath := filepath.Join(temp, "README.md")
+	if err := os.WriteFile(readmePath, []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(temp, "wip.txt"), []byte("wip\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	runGit(t, temp, "add", "wip.txt")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("API should not be called when the index is dirty")
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"-m", "x", "--commit"}, io.Discard, stderr)
+	if code == 0 || !strings.Contains(stderr.String(), "staged changes") {
+		t.Fatalf("expected dirty index failure, exit=%d stderr=%q", code, stderr.String())
+	}
+
+	content, err := os.ReadFile(readmePath)
+	if err != nil {
+		t.Fatalf("read README: %v", err)
+	}
+	if readme.HasBadge(string(content)) {
+		t.Fatalf("README should be untouched when --commit preconditions fail")
+	}
+}
+
+func configureGitUser(t *testing.T, dir string) {
+	t.Helper()
+	runGit(t, dir, "config", "user.name", "Test")
+	runGit(t, dir, "config", "user.email", "test@example.com")
+	runGit(t, dir, "config", "commit.gpgsign", "false")
+}
+
+func TestIntegrationPullRequestFlow(t *testing.T) {
+	temp := t.TempDir()
+	remote := filepath.Join(t.TempDir(), "demo.git")
+	runGit(t, temp, "init", "--quiet", "--bare", remote)
+
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+	runGit(t, temp, "config", "remote.origin.pushurl", remote)
+	configureGitUser(t, temp)
+	runGit(t, temp, "checkout", "--quiet", "-b", "main")
+
+	readmePath := filepath.Join(temp, "README.md")
+	if err := os.WriteFile(readmePath, []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	runGit(t, temp, "add", "README.md")
+	runGit(t, temp, "commit", "--quiet", "-m", "init")
+
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer registry.Close()
+	t.Setenv(apiBaseEnv, registry.URL)
+
+	var pr map[string]string
+	gh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/arrno/demo/pulls" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer gh-token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"number":7,"html_url":"https://github.com/arrno/demo/pull/7"}`))
+	}))
+	defer gh.Close()
+	t.Setenv(githubAPIEnv, gh.URL)
+	t.Setenv("BFAST_GITHUB_TOKEN", "gh-token")
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"-m", "Zero-copy everything", "--pr", "--json"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+
+	var res result
+	if err := json.Unmarshal([]byte(stdout.String()), &res); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if res.PullRequest != "https://github.com/arrno/demo/pull/7" || res.Branch != defaultPRBranch {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	if pr["head"] != defaultPRBranch || pr["base"] != "main" {
+		t.Fatalf("unexpected pull request branches: %v", pr)
+	}
+	if pr["title"] != "Add blazingly fast badge: Zero-copy everything" || !strings.Contains(pr["body"], "> Zero-copy everything") {
+		t.Fatalf("unexpected pull request text: %v", pr)
+	}
+
+	out, err := exec.Command("git", "-C", remote, "log", "-1", "--format=%s", defaultPRBranch).Output()
+	if err != nil {
+		t.Fatalf("git log on remote: %v", err)
+	}
+	if string(out) != defaultCommitMessage+"\n" {
+		t.Fatalf("unexpected pushed commit: %q", string(out))
+	}
+
+	head, err := exec.Command("git", "-C", temp, "symbolic-ref", "--short", "HEAD").Output()
+	if err != nil {
+		t.Fatalf("git symbolic-ref: %v", err)
+	}
+	if string(head) != "main\n" {
+		t.Fatalf("expected to be back on main, got %q", string(head))
+	}
+}
+
+func TestIntegrationPullRequestRecoversFromFailures(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+	configureGitUser(t, temp)
+	runGit(t, temp, "checkout", "--quiet", "-b", "main")
+	readmePath := filepath.Join(temp, "README.md")
+	if err := os.WriteFile(readmePath, []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	runGit(t, temp, "add", "README.md")
+	runGit(t, temp, "commit", "--quiet", "-m", "init")
+	t.Setenv("BFAST_GITHUB_TOKEN", "gh-token")
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	branches := func() string {
+		out, err := exec.Command("git", "-C", temp, "branch", "--format=%(HEAD)%(refname:short)").Output()
+		if err != nil {
+			t.Fatalf("git branch: %v", err)
+		}
+		return strings.Join(strings.Fields(string(out)), ",")
+	}
+
+	// A leftover branch from an earlier run is refused before anything is written.
+	runGit(t, temp, "branch", defaultPRBranch)
+	stderr := &strings.Builder{}
+	if code := Run(context.Background(), []string{"badge", "--pr"}, io.Discard, stderr); code == 0 || !strings.Contains(stderr.String(), "already exists") {
+		t.Fatalf("exit = %d, stderr=%q", code, stderr.String())
+	}
+	if content, _ := os.ReadFile(readmePath); string(content) != "# Demo\n" {
+		t.Fatalf("README changed: %q", content)
+	}
+	runGit(t, temp, "branch", "-D", defaultPRBranch)
+
+	// A failing commit returns to the starting branch and drops the new one.
+	hook := filepath.Join(temp, ".git", "hooks", "pre-commit")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatalf("write hook: %v", err)
+	}
+	stderr.Reset()
+	if code := Run(context.Background(), []string{"badge", "--pr"}, io.Discard, stderr); code == 0 {
+		t.Fatalf("exit = 0 with a failing pre-commit hook, stderr=%q", stderr.String())
+	}
+	if got := branches(); got != "*main" {
+		t.Fatalf("branches after failure = %s, want *main", got)
+	}
+}
+
+func TestIntegrationPullRequestPushesToTheTargetRemote(t *testing.T) {
+	cases := []struct {
+		name    string
+		remotes [][2]string
+		push    string
+		args    []string
+		head    string
+	}{
+		{
+			name:    "remote not named origin",
+			remotes: [][2]string{{"github", "https://github.com/arrno/demo.git"}},
+			push:    "github",
+			args:    []string{"badge", "--pr", "--json"},
+			head:    defaultPRBranch,
+		},
+		{
+			name:    "fork opens a cross-repository pull request",
+			remotes: [][2]string{{"origin", "https://github.com/someone/demo.git"}, {"upstream", "https://github.com/arrno/demo.git"}},
+			push:    "origin",
+			args:    []string{"badge", "--pr", "--repo", "arrno/demo", "--json"},
+			head:    "someone:" + defaultPRBranch,
+		},
+		{
+			name:    "selected remote",
+			remotes: [][2]string{{"origin", "https://github.com/someone/demo.git"}, {"upstream", "https://github.com/arrno/demo.git"}},
+			push:    "upstream",
+			args:    []string{"badge", "--pr", "--remote", "upstream", "--json"},
+			head:    defaultPRBranch,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			temp := t.TempDir()
+			runGit(t, temp, "init", "--quiet")
+			bare := map[string]string{}
+			for _, r := range tc.remotes {
+				bare[r[0]] = filepath.Join(t.TempDir(), r[0]+".git")
+				runGit(t, temp, "init", "--quiet", "--bare", bare[r[0]])
+				runGit(t, temp, "remote", "add", r[0], r[1])
+				runGit(t, temp, "config", "remote."+r[0]+".pushurl", bare[r[0]])
+			}
+			configureGitUser(t, temp)
+			runGit(t, temp, "checkout", "--quiet", "-b", "main")
+			if err := os.WriteFile(filepath.Join(temp, "README.md"), []byte("# Demo\n"), 0o644); err != nil {
+				t.Fatalf("write README: %v", err)
+			}
+			runGit(t, temp, "add", "README.md")
+			runGit(t, temp, "commit", "--quiet", "-m", "init")
+
+			var pr map[string]string
+			gh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/repos/arrno/demo/pulls" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
+					t.Errorf("decode: %v", err)
+				}
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{"number":7,"html_url":"https://github.com/arrno/demo/pull/7"}`))
+			}))
+			defer gh.Close()
+			t.Setenv(githubAPIEnv, gh.URL)
+			t.Setenv("BFAST_GITHUB_TOKEN", "gh-token")
+
+			cwd, _ := os.Getwd()
+			t.Cleanup(func() { _ = os.Chdir(cwd) })
+			if err := os.Chdir(temp); err != nil {
+				t.Fatalf("chdir: %v", err)
+			}
+
+			stdout := &strings.Builder{}
+			stderr := &strings.Builder{}
+			if code := Run(context.Background(), tc.args, stdout, stderr); code != 0 {
+				t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+			}
+			if pr["head"] != tc.head {
+				t.Fatalf("head = %q, want %q", pr["head"], tc.head)
+			}
+			for name, dir := range bare {
+				err := exec.Command("git", "-C", dir, "rev-parse", "--verify", "--quiet", defaultPRBranch).Run()
+				if pushed := err == nil; pushed != (name == tc.push) {
+					t.Fatalf("branch on remote %s = %v, want it only on %s", name, pushed, tc.push)
+				}
+			}
+		})
+	}
+}
+
+func TestIntegrationPullRequestNeedsToken(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+	readmePath := filepath.Join(temp, "README.md")
+	if err := os.WriteFile(readmePath, []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	for _, name := range githubTokenEnvs {
+		t.Setenv(name, "")
+	}
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"badge", "--pr"}, io.Discard, stderr)
+	if code == 0 || !strings.Contains(stderr.String(), "GitHub token") {
+		t.Fatalf("expected missing token failure, exit=%d stderr=%q", code, stderr.String())
+	}
+}
+
+func TestIntegrationCheckReportsAnnotations(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+	content := "# Demo\n\n" + readme.BuildBadgeMarkdown("someone%2Felse") + "\n"
+	if err := os.WriteFile(filepath.Join(temp, "README.md"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("API should not be called by check")
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	code := Run(context.Background(), []string{"check", "--format", "github"}, stdout, io.Discard)
+	if code != exitCheckFailed {
+		t.Fatalf("exit = %d, want %d (stdout=%q)", code, exitCheckFailed, stdout.String())
+	}
+	want := "::error file=README.md,line=3,title=bfast repo_mismatch::badge points at someone/else, want arrno/demo\n"
+	if stdout.String() != want {
+		t.Fatalf("unexpected annotations:\n%s", stdout.String())
+	}
+
+	if err := os.WriteFile(filepath.Join(temp, "README.md"), []byte("# Demo "+readme.BuildBadgeMarkdown("arrno%2Fdemo")+"\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	stdout.Reset()
+	if code := Run(context.Background(), []string{"check", "--json"}, stdout, io.Discard); code != 0 {
+		t.Fatalf("exit = %d for a correct badge, stdout=%q", code, stdout.String())
+	}
+	var res checkResult
+	if err := json.Unmarshal([]byte(stdout.String()), &res); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if !res.OK || res.Badges != 1 || len(res.Findings) != 0 {
+		t.Fatalf("unexpected check result: %+v", res)
+	}
+}
+
+func TestIntegrationJSONKeepsDiagnosticsOffStderr(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+	readmePath := filepath.Join(temp, "README.md")
+	if err := os.WriteFile(readmePath, []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"--force-badge", "--json"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+	if stderr.Len() != 0 {
+		t.Fatalf("expected nothing on stderr with --json, got %q", stderr.String())
+	}
+
+	var res result
+	if err := json.Unmarshal([]byte(stdout.String()), &res); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if len(res.Notices) != 1 || !strings.Contains(res.Notices[0], "No blurb provided") {
+		t.Fatalf("unexpected notices: %q", res.Notices)
+	}
+	if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0], "--force-badge") {
+		t.Fatalf("unexpected warnings: %q", res.Warnings)
+	}
+
+	if err := os.WriteFile(readmePath, []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	stdout.Reset()
+	code = Run(context.Background(), []string{"--force-badge"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stderr=%q", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "No blurb provided") || !strings.Contains(stderr.String(), "Warning: registration failed") {
+		t.Fatalf("expected diagnostics on stderr in text mode, got %q", stderr.String())
+	}
+}
+
+func TestIntegrationVerboseLogsDetection(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+	runGit(t, temp, "remote", "add", "mirror", "https://git.example.com/arrno/demo.git")
+	if err := os.WriteFile(filepath.Join(temp, "README.md"), []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"badge", "--dry-run", "-v", "--log-format", "json"}, io.Discard, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stderr=%q", code, stderr.String())
+	}
+
+	var parsed, skipped, found bool
+	for _, line := range strings.Split(strings.TrimSpace(stderr.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line is not JSON: %q", line)
+		}
+		switch entry["msg"] {
+		case "parsed remote":
+			parsed = entry["slug"] == "arrno/demo"
+		case "skipping remote":
+			skipped = entry["name"] == "mirror"
+		case "found README":
+			found = true
+		}
+	}
+	if !parsed || !skipped || !found {
+		t.Fatalf("missing expected log entries (parsed=%t skipped=%t found=%t):\n%s", parsed, skipped, found, stderr.String())
+	}
+
+	stderr.Reset()
+	if code := Run(context.Background(), []string{"badge", "--dry-run"}, io.Discard, stderr); code != 0 || strings.Contains(stderr.String(), "parsed remote") {
+		t.Fatalf("expected no debug logs by default, exit=%d stderr=%q", code, stderr.String())
+	}
+
+	if code := Run(context.Background(), []string{"badge", "--log-level", "loud"}, io.Discard, io.Discard); code != exitUsage {
+		t.Fatalf("exit = %d for an unknown log level, want %d", code, exitUsage)
+	}
+}
+
+func TestIntegrationTraceHTTPWritesRedactedHAR(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+	if err := os.WriteFile(filepath.Join(temp, "README.md"), []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"message":"deploying"}`))
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	if err := os.MkdirAll(filepath.Join(configDir, "bfast"), 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	userConfig := "profile = \"ci\"\n\n[profile.ci]\ntoken = \"tok-123456\"\n"
+	if err := os.WriteFile(filepath.Join(configDir, "bfast", "config"), []byte(userConfig), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	tracePath := filepath.Join(t.TempDir(), "trace.har")
+	code := Run(context.Background(), []string{"register", "-m", "Traced", "--retries", "0", "--trace-http", tracePath}, io.Discard, io.Discard)
+	if code != exitAPIUnavailable {
+		t.Fatalf("exit = %d, want %d", code, exitAPIUnavailable)
+	}
+
+	data, err := os.ReadFile(tracePath)
+	if err != nil {
+		t.Fatalf("read trace: %v", err)
+	}
+	if strings.Contains(string(data), "tok-123456") {
+		t.Fatalf("trace leaks the token:\n%s", data)
+	}
+
+	var doc har.Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("decode trace: %v", err)
+	}
+	if len(doc.Log.Entries) != 1 {
+		t.Fatalf("entries = %d, want 1", len(doc.Log.Entries))
+	}
+	entry := doc.Log.Entries[0]
+	if entry.Response == nil || entry.Response.Status != http.StatusServiceUnavailable || !strings.Contains(entry.Response.Content.Text, "deploying") {
+		t.Fatalf("unexpected response: %+v", entry.Response)
+	}
+	if entry.Request.PostData == nil || !strings.Contains(entry.Request.PostData.Text, `"blurb":"Traced"`) {
+		t.Fatalf("unexpected request: %+v", entry.Request)
+	}
+
+	// A trace file that cannot be written fails before any request, through
+	// the JSON error payload rather than a line on stderr.
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	missing := filepath.Join(t.TempDir(), "missing", "trace.har")
+	code = Run(context.Background(), []string{"register", "-m", "Traced", "--json", "--trace-http", missing}, stdout, stderr)
+	if code != exitFailure || stderr.Len() != 0 {
+		t.Fatalf("exit = %d, stderr=%q", code, stderr.String())
+	}
+	var payload errorPayload
+	if err := json.Unmarshal([]byte(stdout.String()), &payload); err != nil || !strings.Contains(payload.Error, "cannot write HTTP trace") {
+		t.Fatalf("unexpected error payload %q: %v", stdout.String(), err)
+	}
+}
+
+func TestIntegrationReportsRetryAttempts(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		if n == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	code := Run(context.Background(), []string{"register", "-m", "Persistent", "--json"}, stdout, io.Discard)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q", code, stdout.String())
+	}
+
+	var res result
+	if err := json.Unmarshal([]byte(stdout.String()), &res); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if !res.Registered || res.Attempts != 2 {
+		t.Fatalf("expected registration after 2 attempts: %+v", res)
+	}
+}
+
+func TestIntegrationDeviceLoginStoresToken(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(tokenEnv, "")
+
+	var mu sync.Mutex
+	polls := 0
+	var authHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/api/auth/device/code":
+			_, _ = w.Write([]byte(`{"device_code":"dev-1","user_code":"ABCD-1234","verification_uri":"https://example.test/device","expires_in":60,"interval":0}`))
+		case "/api/auth/device/token":
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["device_code"] != "dev-1" {
+				t.Errorf("unexpected device code %q", body["device_code"])
+			}
+			polls++
+			if polls == 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"authorization_pending"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"tok-device","token_type":"bearer"}`))
+		case "/api/project":
+			authHeader = r.Header.Get("Authorization")
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	if code := Run(context.Background(), []string{"login"}, stdout, stderr); code != 0 {
+		t.Fatalf("login exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "ABCD-1234") || !strings.Contains(stderr.String(), "https://example.test/device") {
+		t.Fatalf("expected login instructions, got %q", stderr.String())
+	}
+	if polls != 2 {
+		t.Fatalf("polls = %d, want 2", polls)
+	}
+
+	path, _ := config.CredentialsPath()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat credentials: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("credentials mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	if code := Run(context.Background(), []string{"register", "--repo", "arrno/demo", "-m", "Authed"}, io.Discard, io.Discard); code != 0 {
+		t.Fatalf("register exit = %d", code)
+	}
+	if authHeader != "Bearer tok-device" {
+		t.Fatalf("Authorization = %q, want the stored token", authHeader)
+	}
+
+	t.Setenv(tokenEnv, "tok-env")
+	if code := Run(context.Background(), []string{"register", "--repo", "arrno/demo", "-m", "Authed"}, io.Discard, io.Discard); code != 0 {
+		t.Fatalf("register exit = %d", code)
+	}
+	if authHeader != "Bearer tok-env" {
+		t.Fatalf("Authorization = %q, want %s to override", authHeader, tokenEnv)
+	}
+
+	stdout.Reset()
+	if code := Run(context.Background(), []string{"logout"}, stdout, io.Discard); code != 0 || !strings.Contains(stdout.String(), "Logged out") {
+		t.Fatalf("logout exit = %d, stdout=%q", code, stdout.String())
+	}
+	if cred, _ := config.LoadCredential(config.DefaultCredential); cred != nil {
+		t.Fatalf("credential still stored after logout: %+v", cred)
+	}
+}
+
+func TestIntegrationLoginWithPastedToken(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(tokenEnv, "")
+
+	prev := stdin
+	t.Cleanup(func() { stdin = prev })
+	stdin = strings.NewReader("  tok-pasted\n")
+
+	stdout := &strings.Builder{}
+	code := Run(context.Background(), []string{"login", "--with-token", "--json"}, stdout, io.Discard)
+	if code != 0 {
+		t.Fatalf("login exit = %d, stdout=%q", code, stdout.String())
+	}
+
+	var res loginResult
+	if err := json.Unmarshal([]byte(stdout.String()), &res); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if res.Method != "token" || res.Profile != config.DefaultCredential {
+		t.Fatalf("unexpected login result: %+v", res)
+	}
+	cred, err := config.LoadCredential(config.DefaultCredential)
+	if err != nil || cred == nil || cred.Token != "tok-pasted" {
+		t.Fatalf("unexpected stored credential: %+v, %v", cred, err)
+	}
+
+	stdin = strings.NewReader("")
+	if code := Run(context.Background(), []string{"login", "--with-token"}, io.Discard, io.Discard); code == 0 {
+		t.Fatal("expected an empty token to fail")
+	}
+}
+
+func TestIntegrationStoredTokenOnlyGoesToItsAPI(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(tokenEnv, "")
+
+	auth := make(chan string, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth <- r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusCreated)
+	})
+	home := httptest.NewServer(handler)
+	defer home.Close()
+	other := httptest.NewServer(handler)
+	defer other.Close()
+
+	if err := config.SaveCredential(config.DefaultCredential, config.Credential{Token: "tok-home", BaseURL: home.URL + "/"}); err != nil {
+		t.Fatalf("save credential: %v", err)
+	}
+
+	for _, tc := range []struct {
+		base, want string
+	}{
+		{home.URL, "Bearer tok-home"},
+		{other.URL, ""},
+	} {
+		t.Setenv(apiBaseEnv, tc.base)
+		stderr := &strings.Builder{}
+		if code := Run(context.Background(), []string{"register", "arrno/demo", "-m", "Fast"}, io.Discard, stderr); code != 0 {
+			t.Fatalf("%s: exit = %d, stderr=%q", tc.base, code, stderr.String())
+		}
+		if got := <-auth; got != tc.want {
+			t.Fatalf("%s: Authorization = %q, want %q", tc.base, got, tc.want)
+		}
+		if warned := strings.Contains(stderr.String(), "Warning: not sending the stored login token"); warned != (tc.want == "") {
+			t.Fatalf("%s: unexpected stderr %q", tc.base, stderr.String())
+		}
+	}
+
+	// With --json the warning is part of the result, not a log line.
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	if code := Run(context.Background(), []string{"register", "arrno/demo", "-m", "Fast", "--json"}, stdout, stderr); code != 0 {
+		t.Fatalf("--json: exit = %d, stderr=%q", code, stderr.String())
+	}
+	<-auth
+	var res result
+	if err := json.Unmarshal([]byte(stdout.String()), &res); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if stderr.Len() != 0 || len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0], "stored login token") {
+		t.Fatalf("warnings = %q, stderr = %q", res.Warnings, stderr.String())
+	}
+}
+
+func TestIntegrationVerifyPublishesChallengeBeforeRegistering(t *testing.T) {
+	temp := t.TempDir()
+	remote := filepath.Join(t.TempDir(), "demo.git")
+	runGit(t, temp, "init", "--quiet", "--bare", remote)
+
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+	runGit(t, temp, "config", "remote.origin.pushurl", remote)
+	configureGitUser(t, temp)
+	runGit(t, temp, "checkout", "--quiet", "-b", "main")
+	runGit(t, temp, "commit", "--quiet", "--allow-empty", "-m", "init")
+
+	var paths []string
+	var sub submission
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/api/project/challenge":
+			_, _ = w.Write([]byte(`{"challenge":"bf-challenge-123"}`))
+		case "/api/project/verify":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("decode: %v", err)
+			}
+			out, err := exec.Command("git", "-C", remote, "show", body["ref"]+":.well-known/blazingly-fast").Output()
+			verified := err == nil && strings.TrimSpace(string(out)) == body["challenge"]
+			_ = json.NewEncoder(w).Encode(map[string]bool{"verified": verified})
+		default:
+			if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+				t.Errorf("decode: %v", err)
+			}
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"register", "-m", "Verified fast", "--verify", "--json"}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+
+	var res result
+	if err := json.Unmarshal([]byte(stdout.String()), &res); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if !res.Verified || !res.Registered || res.VerificationFile != ".well-known/blazingly-fast" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if strings.Join(paths, ",") != "/api/project/challenge,/api/project/verify,/api/project" {
+		t.Fatalf("unexpected request order: %v", paths)
+	}
+	if sub.Challenge != "bf-challenge-123" {
+		t.Fatalf("submission missing challenge: %+v", sub)
+	}
+
+	// Only the challenge was published, on a branch that is gone once
+	// verified; the unpushed main branch and the checkout are untouched.
+	if refs, _ := exec.Command("git", "-C", remote, "for-each-ref").Output(); len(refs) != 0 {
+		t.Fatalf("remote still has refs:\n%s", refs)
+	}
+	if log, _ := exec.Command("git", "-C", temp, "log", "--format=%s").Output(); string(log) != "init\n" {
+		t.Fatalf("local history changed: %q", log)
+	}
+	if _, err := os.Stat(filepath.Join(temp, ".well-known")); !os.IsNotExist(err) {
+		t.Fatalf("challenge written to the checkout: %v", err)
+	}
+}
+
+func TestIntegrationVerifyFailureSkipsRegistration(t *testing.T) {
+	temp := t.TempDir()
+	remote := filepath.Join(t.TempDir(), "demo.git")
+	runGit(t, temp, "init", "--quiet", "--bare", remote)
+
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+	runGit(t, temp, "config", "remote.origin.pushurl", remote)
+	configureGitUser(t, temp)
+	runGit(t, temp, "checkout", "--quiet", "-b", "main")
+	runGit(t, temp, "commit", "--quiet", "--allow-empty", "-m", "init")
+
+	submitted := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/project/challenge":
+			_, _ = w.Write([]byte(`{"challenge":"bf-challenge-123","path":"docs/.bfast"}`))
+		case "/api/project/verify":
+			_, _ = w.Write([]byte(`{"verified":false,"message":"token not found"}`))
+		default:
+			submitted = true
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	code := Run(context.Background(), []string{"register", "--verify", "--json"}, stdout, io.Discard)
+	if code != exitAPIRejected {
+		t.Fatalf("exit = %d, want %d (stdout=%q)", code, exitAPIRejected, stdout.String())
+	}
+	if !strings.Contains(stdout.String(), `"code":"verification_failed"`) {
+		t.Fatalf("unexpected error payload: %s", stdout.String())
+	}
+	if submitted {
+		t.Fatalf("registration should not be submitted after failed verification")
+	}
+	if !strings.Contains(stdout.String(), "git push origin --delete bfast/verify") {
+		t.Fatalf("leftover challenge branch not reported: %s", stdout.String())
+	}
+	out, err := exec.Command("git", "-C", remote, "show", "bfast/verify:docs/.bfast").Output()
+	if err != nil || string(out) != "bf-challenge-123\n" {
+		t.Fatalf("challenge not pushed at the API path: %q, %v", out, err)
+	}
+	if log, _ := exec.Command("git", "-C", temp, "log", "--format=%s").Output(); string(log) != "init\n" {
+		t.Fatalf("local history changed: %q", log)
+	}
+}
+
+func TestIntegrationVerifyRefusesAnotherRepositorysRemote(t *testing.T) {
+	temp := t.TempDir()
+	remote := filepath.Join(t.TempDir(), "fork.git")
+	runGit(t, temp, "init", "--quiet", "--bare", remote)
+
+	initGitRepo(t, temp, "https://github.com/someone/demo.git")
+	runGit(t, temp, "config", "remote.origin.pushurl", remote)
+
+	requested := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"register", "--verify", "--repo", "arrno/demo"}, io.Discard, stderr)
+	if code == 0 || !strings.Contains(stderr.String(), "remote origin, which is someone/demo, not arrno/demo") {
+		t.Fatalf("exit = %d, stderr=%q", code, stderr.String())
+	}
+	if requested {
+		t.Fatalf("no API call should be made when --verify is refused")
+	}
+	if out, _ := exec.Command("git", "-C", remote, "for-each-ref").Output(); len(out) != 0 {
+		t.Fatalf("refs pushed to the fork: %s", out)
+	}
+}
+
+// writeServerPEM saves the certificate and key of a TLS test server as PEM
+// files, so it can be trusted as a CA and reused as a client certificate.
+func writeServerPEM(t *testing.T, srv *httptest.Server) (certPath, keyPath string) {
+	t.Helper()
+	cert := srv.TLS.Certificates[0]
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certPath = filepath.Join(dir, "cert.pem")
+	keyPath = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600); err != nil {
+		t.Fatalf("write cert: %v", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return certPath, keyPath
+}
+
+func TestIntegrationCABundleAndClientCert(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+
+	var peerCerts int
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peerCerts = len(r.TLS.PeerCertificates)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+	certPath, keyPath := writeServerPEM(t, srv)
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stderr := &strings.Builder{}
+	code := Run(context.Background(), []string{"register", "-m", "Fast", "--retries", "0", "--client-cert", certPath, "--client-key", keyPath}, io.Discard, stderr)
+	if code != exitAPIUnavailable {
+		t.Fatalf("untrusted server: exit = %d, want %d (stderr=%q)", code, exitAPIUnavailable, stderr.String())
+	}
+
+	t.Setenv(caBundleEnv, certPath)
+	stderr.Reset()
+	code = Run(context.Background(), []string{"register", "-m", "Fast", "--client-cert", certPath, "--client-key", keyPath}, io.Discard, stderr)
+	if code != 0 {
+		t.Fatalf("run exit = %d, stderr=%q", code, stderr.String())
+	}
+	if peerCerts != 1 {
+		t.Fatalf("server saw %d client certificates, want 1", peerCerts)
+	}
+}
+
+func TestIntegrationTimeoutBoundsTheRun(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://github.com/arrno/demo.git")
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stderr := &strings.Builder{}
+	start := time.Now()
+	code := Run(context.Background(), []string{"register", "-m", "Fast", "--timeout", "200ms"}, io.Discard, stderr)
+	if code != exitAPIUnavailable {
+		t.Fatalf("exit = %d, want %d (stderr=%q)", code, exitAPIUnavailable, stderr.String())
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("run took %s despite --timeout", elapsed)
+	}
+	if !strings.Contains(stderr.String(), "timed out after 200ms") {
+		t.Fatalf("stderr missing timeout hint: %q", stderr.String())
+	}
+}
+
+func TestIntegrationForkDetection(t *testing.T) {
+	newFork := func(t *testing.T) string {
+		temp := t.TempDir()
+		initGitRepo(t, temp, "https://github.com/someone/demo.git")
+		runGit(t, temp, "remote", "add", "upstream", "git@github.com:arrno/demo.git")
+		if err := os.WriteFile(filepath.Join(temp, "README.md"), []byte("# Demo\n"), 0o644); err != nil {
+			t.Fatalf("write README: %v", err)
+		}
+		cwd, _ := os.Getwd()
+		t.Cleanup(func() { _ = os.Chdir(cwd) })
+		if err := os.Chdir(temp); err != nil {
+			t.Fatalf("chdir: %v", err)
+		}
+		return temp
+	}
+	run := func(args ...string) (int, result, string) {
+		stdout := &strings.Builder{}
+		stderr := &strings.Builder{}
+		code := Run(context.Background(), append([]string{"badge", "--json"}, args...), stdout, stderr)
+		var res result
+		_ = json.Unmarshal([]byte(stdout.String()), &res)
+		return code, res, stdout.String()
+	}
+
+	t.Run("warn", func(t *testing.T) {
+		newFork(t)
+		code, res, out := run()
+		if code != 0 || res.Repo != "someone/demo" || res.Remote != "origin" || res.ForkOf != "arrno/demo" {
+			t.Fatalf("exit = %d, output = %s", code, out)
+		}
+		if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0], "looks like a fork") {
+			t.Fatalf("warnings = %q", res.Warnings)
+		}
+
+		stderr := &strings.Builder{}
+		if code := Run(context.Background(), []string{"badge", "--dry-run"}, io.Discard, stderr); code != 0 {
+			t.Fatalf("text run exit = %d", code)
+		}
+		if !strings.Contains(stderr.String(), "Warning: someone/demo looks like a fork") {
+			t.Fatalf("text output does not warn about the fork: %q", stderr.String())
+		}
+	})
+
+	t.Run("refuse", func(t *testing.T) {
+		temp := newFork(t)
+		code, _, out := run("--fork-check", "refuse")
+		if code != exitRepoDetection || !strings.Contains(out, `"code":"fork_refused"`) {
+			t.Fatalf("exit = %d, output = %s", code, out)
+		}
+		content, _ := os.ReadFile(filepath.Join(temp, "README.md"))
+		if readme.HasBadge(string(content)) {
+			t.Fatal("README was badged despite the refusal")
+		}
+	})
+
+	t.Run("explicit remote", func(t *testing.T) {
+		newFork(t)
+		code, res, out := run("--remote", "upstream", "--fork-check", "refuse")
+		if code != 0 || res.Repo != "arrno/demo" || res.Remote != "upstream" || len(res.Warnings) != 0 {
+			t.Fatalf("exit = %d, output = %s", code, out)
+		}
+		if code, _, out := run("--remote", "nope"); code != exitRepoDetection || !strings.Contains(out, "remote_not_found") {
+			t.Fatalf("exit = %d, output = %s", code, out)
+		}
+	})
+
+	t.Run("api overrides heuristic", func(t *testing.T) {
+		newFork(t)
+		gh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/repos/someone/demo" {
+				t.Errorf("unexpected path %s", r.URL.Path)
+			}
+			_, _ = w.Write([]byte(`{"full_name":"someone/demo","fork":false}`))
+		}))
+		defer gh.Close()
+		t.Setenv(githubAPIEnv, gh.URL)
+
+		code, res, out := run("--fork-api", "--fork-check", "refuse")
+		if code != 0 || res.ForkOf != "" || len(res.Warnings) != 0 {
+			t.Fatalf("exit = %d, output = %s", code, out)
+		}
+	})
+}
+
+func TestIntegrationRegistersGitLabSubgroupRepo(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "git@gitlab.com:group/sub/demo.git")
+	readmePath := filepath.Join(temp, "README.md")
+	if err := os.WriteFile(readmePath, []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+
+	var sub submission
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+	t.Setenv(apiBaseEnv, srv.URL)
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	if code := Run(context.Background(), []string{"-m", "Fast"}, stdout, stderr); code != 0 {
+		t.Fatalf("run exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+	if sub.RepoURL != "https://gitlab.com/group/sub/demo" {
+		t.Fatalf("repo url = %s", sub.RepoURL)
+	}
+
+	content, err := os.ReadFile(readmePath)
+	if err != nil {
+		t.Fatalf("read README: %v", err)
+	}
+	if !strings.Contains(string(content), "?repo=gitlab.com%2Fgroup%2Fsub%2Fdemo)") {
+		t.Fatalf("unexpected badge: %s", content)
+	}
+
+	if code := Run(context.Background(), []string{"check"}, stdout, stderr); code != 0 {
+		t.Fatalf("check exit = %d, stdout=%q stderr=%q", code, stdout.String(), stderr.String())
+	}
+}
+
+func TestIntegrationForgesSettingRecognizesSelfHostedRemote(t *testing.T) {
+	temp := t.TempDir()
+	initGitRepo(t, temp, "https://git.example.com/team/demo.git")
+	if err := os.WriteFile(filepath.Join(temp, "README.md"), []byte("# Demo\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	if code := Run(context.Background(), []string{"badge", "--dry-run", "--json"}, stdout, io.Discard); code != exitRepoDetection {
+		t.Fatalf("exit without forges = %d, stdout=%q", code, stdout.String())
+	}
+
+	t.Setenv("BFAST_FORGES", "git.example.com=gitea")
+	stdout.Reset()
+	if code := Run(context.Background(), []string{"badge", "--dry-run", "--json"}, stdout, io.Discard); code != 0 {
+		t.Fatalf("exit = %d, stdout=%q", code, stdout.String())
+	}
+	var res result
+	if err := json.Unmarshal([]byte(stdout.String()), &res); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if res.Repo != "git.example.com/team/demo" || res.RepoURL != "https://git.example.com/team/demo" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
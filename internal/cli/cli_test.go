@@ -3,14 +3,43 @@ package cli
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/arrno/bfast/internal/api"
+	"github.com/arrno/bfast/internal/blurb"
 	"github.com/arrno/bfast/internal/git"
+	"github.com/arrno/bfast/internal/normalize"
+	"github.com/arrno/bfast/internal/readme"
 )
 
+// TestMain points the user config at an empty directory so a developer's own
+// ~/.config/bfast/config cannot leak into tests.
+// TestMain keeps the developer's own configuration out of the tests: the
+// user config, the system and global git config, and ~/.ssh/config.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "bfast-config")
+	if err != nil {
+		panic(err)
+	}
+	home := filepath.Join(dir, "home")
+	if err := os.Mkdir(home, 0o755); err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	os.Setenv("HOME", home)
+	os.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	os.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestExecuteRequiresGitRepo(t *testing.T) {
 	temp := t.TempDir()
 	cwd, err := os.Getwd()
@@ -23,7 +52,7 @@ func TestExecuteRequiresGitRepo(t *testing.T) {
 	}
 
 	opts := &options{}
-	if _, err := execute(context.Background(), opts, io.Discard); err == nil || err != git.ErrNotRepository {
+	if _, err := execute(context.Background(), opts); err == nil || err != git.ErrNotRepository {
 		t.Fatalf("expected ErrNotRepository, got %v", err)
 	}
 }
@@ -52,8 +81,7 @@ func TestExecuteDryRunWithOverrides(t *testing.T) {
 		blurbProvided: true,
 	}
 
-	buf := &bytes.Buffer{}
-	res, err := execute(context.Background(), opts, buf)
+	res, err := execute(context.Background(), opts)
 	if err != nil {
 		t.Fatalf("execute returned error: %v", err)
 	}
@@ -77,12 +105,25 @@ func TestExecuteDryRunWithOverrides(t *testing.T) {
 	if res.Blurb != "fast" {
 		t.Fatalf("expected blurb to be preserved")
 	}
+
+	wantDiff := "--- a/README.md\n+++ b/README.md\n@@ -1 +1,2 @@\n # Title\n+" + res.BadgeMarkdown + "\n"
+	if res.Diff != wantDiff {
+		t.Fatalf("dry run diff = %q, want %q", res.Diff, wantDiff)
+	}
+
+	content, err := os.ReadFile(readmePath)
+	if err != nil {
+		t.Fatalf("read README: %v", err)
+	}
+	if string(content) != "# Title\n" {
+		t.Fatalf("dry run modified README: %q", string(content))
+	}
 }
 
 func TestEmitResultPrefersAlreadyBadgedMessage(t *testing.T) {
 	buf := &bytes.Buffer{}
 	res := &result{AlreadyBadged: true, DryRun: true}
-	emitResult(res, false, buf)
+	emitResult(res, false, buf, io.Discard)
 
 	got := buf.String()
 	want := "Already badged. No changes.\n"
@@ -90,3 +131,230 @@ func TestEmitResultPrefersAlreadyBadgedMessage(t *testing.T) {
 		t.Fatalf("emitResult output = %q, want %q", got, want)
 	}
 }
+
+func TestEmitResultDryRunPrintsPlainDiff(t *testing.T) {
+	buf := &bytes.Buffer{}
+	res := &result{DryRun: true, Repo: "a/b", Readme: "README.md", Diff: "--- a/README.md\n+++ b/README.md\n@@ -1 +1,2 @@\n # T\n+badge\n"}
+	emitResult(res, false, buf, io.Discard)
+
+	want := "Dry run: would register a/b and update README.md\n" + res.Diff
+	if buf.String() != want {
+		t.Fatalf("emitResult output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRunHelpListsCommands(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	if code := Run(context.Background(), []string{"help"}, stdout, io.Discard); code != 0 {
+		t.Fatalf("help exit = %d", code)
+	}
+
+	for _, cmd := range commands {
+		if !strings.Contains(stdout.String(), cmd.name) {
+			t.Fatalf("help output missing %q:\n%s", cmd.name, stdout.String())
+		}
+	}
+}
+
+func TestRunRejectsFlagsFromOtherCommands(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	if code := Run(context.Background(), []string{"badge", "--hidden"}, io.Discard, stderr); code != 2 {
+		t.Fatalf("exit = %d, want 2 (stderr=%q)", code, stderr.String())
+	}
+}
+
+func TestCheckBadgesFindings(t *testing.T) {
+	good := readme.BuildBadgeMarkdown("arrno%2Fdemo")
+	other := readme.BuildBadgeMarkdown("someone%2Felse")
+
+	cases := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"ok", "# Demo " + good + "\n", nil},
+		{"missing", "# Demo\n", []string{"missing_badge@0"}},
+		{"bare url", "# Demo\n\n" + readme.BadgeImageURL + "?repo=arrno%2Fdemo\n", nil},
+		{"multi-line img", "<p align=\"center\">\n  <img\n    src=\"" + readme.BadgeImageURL + "?repo=someone%2Felse\">\n</p>\n", []string{"repo_mismatch@2"}},
+		{"mismatch", "# Demo\n\n" + other + "\n", []string{"repo_mismatch@3"}},
+		{"duplicate", good + "\n\n" + good + "\n", []string{"duplicate_badge@3"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, f := range checkBadges(tc.content, "arrno/demo") {
+				got = append(got, fmt.Sprintf("%s@%d", f.Code, f.Line))
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("findings = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExitCodeClassifiesErrors(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{git.ErrNotRepository, exitNotRepository},
+		{fmt.Errorf("detect: %w", git.ErrAmbiguousRepo), exitRepoDetection},
+		{git.ErrNoRepoRemote, exitRepoDetection},
+		{normalize.ErrInvalidRepo, exitInvalidRepo},
+		{fmt.Errorf("%w: docs/README.md", readme.ErrNotFound), exitReadmeNotFound},
+		{fmt.Errorf("%w: permission denied", errReadmeWrite), exitWriteFailed},
+		{&api.Error{Status: 422, Message: "bad blurb"}, exitAPIRejected},
+		{api.ErrNotRegistered, exitAPIRejected},
+		{&api.Error{Status: 503}, exitAPIUnavailable},
+		{fmt.Errorf("%w: connection refused", api.ErrUnreachable), exitAPIUnavailable},
+		{&exitError{code: exitCheckFailed}, exitCheckFailed},
+		{errors.New("something else"), exitFailure},
+	}
+
+	for _, tc := range cases {
+		if got := exitCode(tc.err); got != tc.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestRunReportsExitCodeInJSONError(t *testing.T) {
+	temp := t.TempDir()
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	if err := os.Chdir(temp); err != nil {
+		t.Fatalf("Chdir failed: %v", err)
+	}
+
+	stdout := &strings.Builder{}
+	code := Run(context.Background(), []string{"--json"}, stdout, io.Discard)
+	if code != exitNotRepository {
+		t.Fatalf("exit = %d, want %d", code, exitNotRepository)
+	}
+	if !strings.Contains(stdout.String(), `"exitCode":4`) {
+		t.Fatalf("JSON error missing exit code: %s", stdout.String())
+	}
+}
+
+func TestDescribeErrorDetails(t *testing.T) {
+	payload := describeError(fmt.Errorf("submit: %w", &api.Error{Status: 422, Message: "blurb too spicy"}), exitAPIRejected)
+	if payload.Code != codeAPIRejected || payload.Status != 422 || payload.ServerMessage != "blurb too spicy" {
+		t.Fatalf("unexpected API payload: %+v", payload)
+	}
+
+	payload = describeError(&api.Error{Status: 409, Err: api.ErrAlreadyRegistered}, exitAPIRejected)
+	if payload.Code != codeAPIConflict || payload.Status != 409 {
+		t.Fatalf("unexpected conflict payload: %+v", payload)
+	}
+
+	payload = describeError(&git.AmbiguousRepoError{Candidates: []string{"a/b", "c/d"}}, exitRepoDetection)
+	if payload.Code != codeAmbiguous || strings.Join(payload.Candidates, ",") != "a/b,c/d" {
+		t.Fatalf("unexpected ambiguous payload: %+v", payload)
+	}
+
+	payload = describeError(&fileError{path: "/tmp/README.md", err: fmt.Errorf("%w: denied", errReadmeWrite)}, exitWriteFailed)
+	if payload.Code != codeWriteFailed || payload.Path != "/tmp/README.md" {
+		t.Fatalf("unexpected file payload: %+v", payload)
+	}
+
+	payload = describeError(blurb.ErrInvalidBlurb, exitFailure)
+	if payload.Code != codeBlurbInvalid {
+		t.Fatalf("unexpected blurb payload: %+v", payload)
+	}
+
+	payload = describeError(errors.New("unexpected argument"), exitUsage)
+	if payload.Code != codeUsage {
+		t.Fatalf("unexpected usage payload: %+v", payload)
+	}
+}
+
+func TestParseArgsInterspersed(t *testing.T) {
+	cases := []struct {
+		name  string
+		args  []string
+		check func(*options) bool
+	}{
+		{"flag after positional", []string{"owner/repo", "--hidden"}, func(o *options) bool {
+			return o.repoInput == "owner/repo" && o.hidden && o.hiddenProvided
+		}},
+		{"equals value", []string{"--blurb=Fast enough", "owner/repo"}, func(o *options) bool {
+			return o.blurb == "Fast enough" && o.repoInput == "owner/repo"
+		}},
+		{"bool with equals", []string{"--hidden=false"}, func(o *options) bool {
+			return !o.hidden && o.hiddenProvided
+		}},
+		{"clustered shorthands", []string{"-vm", "Fast"}, func(o *options) bool {
+			return o.verbose && o.blurb == "Fast"
+		}},
+		{"attached shorthand value", []string{"-mFast"}, func(o *options) bool {
+			return o.blurb == "Fast"
+		}},
+		{"single-dash long flag", []string{"-dry-run", "-json"}, func(o *options) bool {
+			return o.dryRun && o.json
+		}},
+		{"double dash ends flags", []string{"--dry-run", "--", "-weird/repo"}, func(o *options) bool {
+			return o.dryRun && o.repoInput == "-weird/repo"
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := parseArgs(defaultCommand, tc.args, io.Discard)
+			if err != nil {
+				t.Fatalf("parseArgs(%q) error: %v", tc.args, err)
+			}
+			if !tc.check(opts) {
+				t.Fatalf("parseArgs(%q) = %+v", tc.args, opts)
+			}
+		})
+	}
+}
+
+func TestParseArgsExplainsUnknownFlags(t *testing.T) {
+	cases := []struct {
+		cmd  *command
+		args []string
+		want string
+	}{
+		{defaultCommand, []string{"--hiden"}, "did you mean --hidden?"},
+		{defaultCommand, []string{"--dryrun"}, "did you mean --dry-run?"},
+		{defaultCommand, []string{"-vx"}, "unknown flag -x"},
+		{defaultCommand, []string{"--blurb"}, "flag --blurb needs a value"},
+		{defaultCommand, []string{"-blurb"}, "flag -blurb needs a value"},
+		{defaultCommand, []string{"-m"}, "flag -m needs a value"},
+		{defaultCommand, []string{"---hidden"}, "invalid flag ---hidden"},
+		{defaultCommand, []string{"--json", "status"}, `command "status" must come before any flags`},
+		{defaultCommand, []string{"--hidden=maybe"}, `invalid value "maybe" for --hidden`},
+		{defaultCommand, []string{"--jobs", "2"}, "bfast batch accepts it"},
+	}
+
+	for _, tc := range cases {
+		_, err := parseArgs(tc.cmd, tc.args, io.Discard)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("parseArgs(%q) error = %v, want %q", tc.args, err, tc.want)
+		}
+	}
+}
+
+func TestRunSuggestsMistypedCommand(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	if code := Run(context.Background(), []string{"regster"}, io.Discard, stderr); code != exitUsage {
+		t.Fatalf("exit = %d, want %d", code, exitUsage)
+	}
+	if !strings.Contains(stderr.String(), `did you mean "register"?`) {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+}
+
+func TestHelpShowsExamples(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	if code := Run(context.Background(), []string{"help", "update"}, stdout, io.Discard); code != 0 {
+		t.Fatalf("help exit = %d", code)
+	}
+	for _, want := range []string{"-m, --blurb text", "Examples:", "bfast update --hidden=false"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Fatalf("help output missing %q:\n%s", want, stdout.String())
+		}
+	}
+}
@@ -0,0 +1,524 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Placeholders for the positional arguments a command may accept. argRepo
+// and argDir are a single optional argument; anything else is passed through
+// in options.args.
+const (
+	argRepo   = "owner/repo"
+	argDir    = "dir"
+	argConfig = "get <key> | set <key> <value> | list | path"
+)
+
+// command describes a bfast verb along with the flags it accepts.
+type command struct {
+	name       string
+	summary    string
+	flags      []string
+	positional string
+	examples   []string
+	exec       func(ctx context.Context, opts *options, stdout, stderr io.Writer) error
+}
+
+var defaultCommand = &command{
+	summary:    "Register the repo and insert the badge",
+	flags:      []string{"blurb", "repo", "remote", "readme", "hidden", "placement", "profile", "commit", "commit-message", "pr", "pr-branch", "pr-base", "verify", "fork-check", "fork-api", "dry-run", "force-badge", "trace-http", "retries", "timeout", "ca-cert", "client-cert", "client-key", "proxy"},
+	positional: argRepo,
+	examples: []string{
+		`bfast`,
+		`bfast -m "Fast enough for me"`,
+		`bfast owner/repo --hidden --dry-run`,
+		`bfast --pr --pr-base main`,
+	},
+	exec: runDefault,
+}
+
+var commands = []*command{
+	{
+		name:       "register",
+		summary:    "Register the repo with the API without touching the README",
+		flags:      []string{"blurb", "repo", "remote", "hidden", "profile", "verify", "fork-check", "fork-api", "dry-run", "trace-http", "retries", "timeout", "ca-cert", "client-cert", "client-key", "proxy"},
+		positional: argRepo,
+		examples: []string{
+			`bfast register -m "Fast enough"`,
+			`bfast register owner/repo --hidden`,
+			`bfast register --verify`,
+		},
+		exec: runRegister,
+	},
+	{
+		name:       "update",
+		summary:    "Change the blurb or hidden flag of an existing registration",
+		flags:      []string{"blurb", "repo", "remote", "hidden", "profile", "dry-run", "trace-http", "retries", "timeout", "ca-cert", "client-cert", "client-key", "proxy"},
+		positional: argRepo,
+		examples: []string{
+			`bfast update -m "Faster now"`,
+			`bfast update --hidden=false`,
+		},
+		exec: runUpdate,
+	},
+	{
+		name:       "badge",
+		summary:    "Insert the badge into the README without calling the API",
+		flags:      []string{"repo", "remote", "readme", "placement", "commit", "commit-message", "pr", "pr-branch", "pr-base", "dry-run", "fork-check", "fork-api", "trace-http"},
+		positional: argRepo,
+		examples: []string{
+			`bfast badge`,
+			`bfast badge --placement title --commit`,
+		},
+		exec: runBadge,
+	},
+	{
+		name:       "status",
+		summary:    "Report the README badge and API registration state",
+		flags:      []string{"repo", "remote", "readme", "profile", "trace-http", "retries", "timeout", "ca-cert", "client-cert", "client-key", "proxy"},
+		positional: argRepo,
+		examples: []string{
+			`bfast status`,
+			`bfast status owner/repo --json`,
+		},
+		exec: runStatus,
+	},
+	{
+		name:    "remove",
+		summary: "Strip the badge from the README",
+		flags:   []string{"readme", "dry-run"},
+		examples: []string{
+			`bfast remove`,
+			`bfast remove --dry-run`,
+		},
+		exec: runRemove,
+	},
+	{
+		name:       "batch",
+		summary:    "Register and badge every git checkout under a directory",
+		flags:      []string{"blurb", "readme", "hidden", "placement", "profile", "commit", "commit-message", "dry-run", "force-badge", "remote", "fork-check", "fork-api", "jobs", "repos-file", "trace-http", "retries", "timeout", "ca-cert", "client-cert", "client-key", "proxy"},
+		positional: argDir,
+		examples: []string{
+			`bfast batch ~/src`,
+			`bfast batch --repos-file list.txt --jobs 8`,
+		},
+		exec: runBatch,
+	},
+	{
+		name:       "check",
+		summary:    "Lint the README badge for CI without changing anything",
+		flags:      []string{"repo", "remote", "readme", "format"},
+		positional: argRepo,
+		examples: []string{
+			`bfast check`,
+			`bfast check --format github`,
+		},
+		exec: runCheck,
+	},
+	{
+		name:    "doctor",
+		summary: "Check the local environment for common problems",
+		flags:   []string{"repo", "remote", "readme", "profile", "timeout", "ca-cert", "client-cert", "client-key", "proxy"},
+		examples: []string{
+			`bfast doctor`,
+			`bfast doctor --profile staging`,
+		},
+		exec: runDoctor,
+	},
+	{
+		name:    "login",
+		summary: "Sign in and store an API token for the selected profile",
+		flags:   []string{"profile", "with-token", "timeout", "ca-cert", "client-cert", "client-key", "proxy"},
+		examples: []string{
+			`bfast login`,
+			`bfast login --profile staging`,
+			`echo "$TOKEN" | bfast login --with-token`,
+		},
+		exec: runLogin,
+	},
+	{
+		name:    "logout",
+		summary: "Delete the stored API token for the selected profile",
+		flags:   []string{"profile"},
+		examples: []string{
+			`bfast logout`,
+			`bfast logout --profile staging`,
+		},
+		exec: runLogout,
+	},
+	{
+		name:       "config",
+		summary:    "Read and write the user config and API profiles",
+		positional: argConfig,
+		examples: []string{
+			`bfast config list`,
+			`bfast config get profile`,
+			`bfast config set profile.staging.base_url https://staging.blazingly.fast`,
+		},
+		exec: runConfig,
+	},
+}
+
+func lookupCommand(name string) (*command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return nil, false
+}
+
+func (c *command) path() string {
+	if c.name == "" {
+		return "bfast"
+	}
+	return "bfast " + c.name
+}
+
+// helpCommand is the command that prints c's usage.
+func (c *command) helpCommand() string {
+	if c.name == "" {
+		return "bfast help"
+	}
+	return "bfast help " + c.name
+}
+
+func (c *command) usageLine() string {
+	line := c.path() + " [flags]"
+	switch c.positional {
+	case "":
+	case argRepo, argDir:
+		line += " [" + c.positional + "]"
+	default:
+		line += " " + c.positional
+	}
+	return line
+}
+
+type stringValue struct {
+	value string
+	set   bool
+}
+
+func (s *stringValue) Set(v string) error {
+	s.value = v
+	s.set = true
+	return nil
+}
+
+func (s *stringValue) String() string { return s.value }
+
+type boolValue struct {
+	value bool
+	set   bool
+}
+
+func (b *boolValue) Set(v string) error {
+	parsed, err := strconv.ParseBool(v)
+	if err != nil {
+		return err
+	}
+	b.value = parsed
+	b.set = true
+	return nil
+}
+
+func (b *boolValue) String() string { return strconv.FormatBool(b.value) }
+
+func (b *boolValue) IsBoolFlag() bool { return true }
+
+func parseArgs(cmd *command, args []string, stderr io.Writer) (*options, error) {
+	fs := flag.NewFlagSet(cmd.path(), flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { printUsage(stderr, cmd, fs) }
+
+	opts := &options{}
+	var blurbValue stringValue
+	var hiddenValue boolValue
+	var repo string
+	var retriesValue stringValue
+	var forkAPIValue boolValue
+
+	for _, name := range cmd.flags {
+		switch name {
+		case "blurb":
+			fs.Var(&blurbValue, "blurb", "Custom blurb text (max 128 chars)")
+			fs.Var(&blurbValue, "m", "Custom blurb text (shorthand)")
+		case "repo":
+			fs.StringVar(&repo, "repo", "", "Target repository (owner/repo, host/group/repo, or repository URL)")
+		case "remote":
+			fs.StringVar(&opts.remote, "remote", "", "Git remote to take the repository from (default: origin, else the only repository remote)")
+		case "fork-check":
+			fs.StringVar(&opts.forkCheck, "fork-check", "", "When the repository looks like a fork: warn (default), refuse, or off")
+		case "fork-api":
+			fs.Var(&forkAPIValue, "fork-api", "Ask the GitHub API whether the repository is a fork")
+		case "readme":
+			fs.StringVar(&opts.readmeInput, "readme", "", "Path to README (defaults to repo README)")
+		case "hidden":
+			fs.Var(&hiddenValue, "hidden", "Submit as hidden")
+		case "dry-run":
+			fs.BoolVar(&opts.dryRun, "dry-run", false, "Show actions without making changes")
+		case "force-badge":
+			fs.BoolVar(&opts.forceBadge, "force-badge", false, "Insert badge even if API call fails")
+		case "placement":
+			fs.StringVar(&opts.placement, "placement", "", "Badge placement: auto, top, title, or bottom")
+		case "commit":
+			fs.BoolVar(&opts.commit, "commit", false, "Commit the README change with git")
+		case "commit-message":
+			fs.StringVar(&opts.commitMessage, "commit-message", "", "Commit message template; supports {repo}, {blurb}, {readme} (implies --commit)")
+		case "pr":
+			fs.BoolVar(&opts.pr, "pr", false, "Commit on a new branch, push it, and open a pull request (implies --commit)")
+		case "pr-branch":
+			fs.StringVar(&opts.prBranch, "pr-branch", "", "Branch name for --pr (default "+defaultPRBranch+")")
+		case "pr-base":
+			fs.StringVar(&opts.prBase, "pr-base", "", "Base branch for --pr (default: the current branch)")
+		case "verify":
+			fs.BoolVar(&opts.verify, "verify", false, "Prove ownership first by committing and pushing an API challenge token")
+		case "format":
+			fs.StringVar(&opts.format, "format", "", "Output format: text, json, or github (Actions annotations)")
+		case "retries":
+			fs.Var(&retriesValue, "retries", "Retries for API requests that fail with 429, 5xx, or a network error (default 2)")
+		case "with-token":
+			fs.BoolVar(&opts.withToken, "with-token", false, "Read the token from stdin instead of the browser login flow")
+		case "timeout":
+			fs.DurationVar(&opts.timeout, "timeout", 0, "Time limit for the whole command, and for each API request (e.g. 30s)")
+		case "ca-cert":
+			fs.StringVar(&opts.caCert, "ca-cert", "", "PEM bundle of extra CA certificates to trust for API requests")
+		case "client-cert":
+			fs.StringVar(&opts.clientCert, "client-cert", "", "PEM client certificate for mutual TLS with the API")
+		case "client-key":
+			fs.StringVar(&opts.clientKey, "client-key", "", "PEM key for --client-cert (default: read from the certificate file)")
+		case "proxy":
+			fs.StringVar(&opts.proxy, "proxy", "", "Proxy URL for API requests, or \"none\" to ignore HTTP(S)_PROXY")
+		case "trace-http":
+			fs.StringVar(&opts.traceHTTP, "trace-http", "", "Record HTTP requests and responses to a HAR file, with secrets redacted")
+		case "profile":
+			fs.StringVar(&opts.profile, "profile", "", "Named API profile from the user config")
+		case "jobs":
+			fs.IntVar(&opts.jobs, "jobs", defaultBatchJobs, "Number of repositories processed concurrently")
+		case "repos-file":
+			fs.StringVar(&opts.reposFile, "repos-file", "", "File listing repository paths, one per line")
+		default:
+			panic("cli: unknown flag " + name)
+		}
+	}
+	fs.BoolVar(&opts.json, "json", false, "Emit machine-readable JSON output")
+	fs.BoolVar(&opts.verbose, "verbose", false, "Log debug details to stderr (same as --log-level debug)")
+	fs.BoolVar(&opts.verbose, "v", false, "Log debug details to stderr (shorthand)")
+	fs.StringVar(&opts.logLevel, "log-level", "", "Log level: debug, info, warn, or error (default warn)")
+	fs.StringVar(&opts.logFormat, "log-format", logFormatText, "Log format: text or json")
+
+	remainingArgs, err := scanArgs(cmd, fs, args)
+	if err != nil {
+		return opts, err
+	}
+
+	positionalRepo := ""
+	switch cmd.positional {
+	case "":
+		if len(remainingArgs) > 0 {
+			return opts, fmt.Errorf("unexpected argument %q", remainingArgs[0])
+		}
+	case argRepo, argDir:
+		if len(remainingArgs) > 1 {
+			return opts, errors.New("too many positional arguments")
+		}
+		if len(remainingArgs) == 1 {
+			// Run only looks for a command in the first argument, so
+			// "bfast --json status" lands here with status as the repo.
+			if sub, ok := lookupCommand(remainingArgs[0]); ok && cmd == defaultCommand {
+				return opts, fmt.Errorf("command %q must come before any flags, as in \"%s [flags]\"", sub.name, sub.path())
+			}
+			if cmd.positional == argRepo {
+				positionalRepo = remainingArgs[0]
+			} else {
+				opts.batchDir = remainingArgs[0]
+			}
+		}
+	default:
+		opts.args = remainingArgs
+	}
+
+	if repo != "" && positionalRepo != "" {
+		return opts, errors.New("repo provided via --repo and positional argument")
+	}
+	if opts.remote != "" && (repo != "" || positionalRepo != "") {
+		return opts, errors.New("--remote picks the repo from a git remote; it cannot be combined with an explicit repo")
+	}
+
+	targetRepo := repo
+	if targetRepo == "" {
+		targetRepo = positionalRepo
+	}
+
+	opts.blurb = blurbValue.value
+	opts.blurbProvided = blurbValue.set
+	opts.hidden = hiddenValue.value
+	opts.hiddenProvided = hiddenValue.set
+	opts.repoInput = strings.TrimSpace(targetRepo)
+	opts.readmeInput = strings.TrimSpace(opts.readmeInput)
+	opts.placement = strings.TrimSpace(opts.placement)
+	opts.profile = strings.TrimSpace(opts.profile)
+	opts.remote = strings.TrimSpace(opts.remote)
+	opts.forkAPI = forkAPIValue.value
+	opts.forkAPIProvided = forkAPIValue.set
+	if retriesValue.set {
+		retries, err := strconv.Atoi(strings.TrimSpace(retriesValue.value))
+		if err != nil || retries < 0 {
+			return opts, fmt.Errorf("--retries must be a non-negative integer, got %q", retriesValue.value)
+		}
+		opts.retries = retries
+		opts.retriesProvided = true
+	}
+	if opts.timeout < 0 {
+		return opts, fmt.Errorf("--timeout must be positive, got %s", opts.timeout)
+	}
+	opts.caCert = strings.TrimSpace(opts.caCert)
+	opts.clientCert = strings.TrimSpace(opts.clientCert)
+	opts.clientKey = strings.TrimSpace(opts.clientKey)
+	opts.proxy = strings.TrimSpace(opts.proxy)
+	opts.traceHTTP = strings.TrimSpace(opts.traceHTTP)
+	opts.format = strings.ToLower(strings.TrimSpace(opts.format))
+	opts.prBranch = strings.TrimSpace(opts.prBranch)
+	opts.prBase = strings.TrimSpace(opts.prBase)
+	if opts.commitMessage != "" || opts.pr {
+		opts.commit = true
+	}
+
+	return opts, nil
+}
+
+func printUsage(w io.Writer, cmd *command, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "Usage:\n  %s\n", cmd.usageLine())
+	if cmd == defaultCommand {
+		fmt.Fprintf(w, "  bfast <command> [flags]\n")
+	}
+	fmt.Fprintf(w, "\n%s.\n", cmd.summary)
+
+	if cmd == defaultCommand {
+		fmt.Fprintln(w, "\nCommands:")
+		printCommandList(w)
+	}
+
+	fmt.Fprintln(w, "\nFlags:")
+	printFlags(w, fs)
+
+	if len(cmd.examples) > 0 {
+		fmt.Fprintln(w, "\nExamples:")
+		for _, example := range cmd.examples {
+			fmt.Fprintf(w, "  %s\n", example)
+		}
+	}
+
+	if cmd == defaultCommand {
+		fmt.Fprintln(w, "\nRun \"bfast help <command>\" for command-specific flags.")
+	}
+}
+
+// flagPlaceholders names the value of each flag in usage output.
+var flagPlaceholders = map[string]string{
+	"blurb":          "text",
+	"repo":           "owner/repo",
+	"remote":         "name",
+	"fork-check":     "mode",
+	"readme":         "path",
+	"placement":      "where",
+	"commit-message": "template",
+	"pr-branch":      "branch",
+	"pr-base":        "branch",
+	"format":         "format",
+	"retries":        "n",
+	"trace-http":     "file",
+	"profile":        "name",
+	"jobs":           "n",
+	"repos-file":     "file",
+	"timeout":        "duration",
+	"ca-cert":        "file",
+	"client-cert":    "file",
+	"client-key":     "file",
+	"proxy":          "url",
+	"log-level":      "level",
+	"log-format":     "format",
+}
+
+// printFlags lists the flags of fs in GNU style, one per line, with each
+// shorthand next to its long form.
+func printFlags(w io.Writer, fs *flag.FlagSet) {
+	shortFor := map[string]string{}
+	for short, long := range shorthands {
+		if fs.Lookup(short) != nil {
+			shortFor[long] = short
+		}
+	}
+
+	type row struct{ names, usage string }
+	var rows []row
+	width := 0
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, ok := shorthands[f.Name]; ok {
+			return
+		}
+		names := "    --" + f.Name
+		if short, ok := shortFor[f.Name]; ok {
+			names = "-" + short + ", --" + f.Name
+		}
+		if !isBool(f) {
+			placeholder := flagPlaceholders[f.Name]
+			if placeholder == "" {
+				placeholder = "value"
+			}
+			names += " " + placeholder
+		}
+		usage := f.Usage
+		switch f.DefValue {
+		case "", "false", "0", "0s":
+		default:
+			usage += fmt.Sprintf(" (default %s)", f.DefValue)
+		}
+		rows = append(rows, row{names, usage})
+		width = max(width, len(names))
+	})
+
+	for _, r := range rows {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, r.names, r.usage)
+	}
+}
+
+func printCommandList(w io.Writer) {
+	width := len("help")
+	for _, cmd := range commands {
+		if len(cmd.name) > width {
+			width = len(cmd.name)
+		}
+	}
+
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "  %-*s  %s\n", width, "help", "Show help for a command")
+}
+
+// runHelp prints usage for the named command, or the top-level usage when no
+// command is given.
+func runHelp(args []string, stdout, stderr io.Writer) int {
+	cmd := defaultCommand
+	if len(args) > 0 {
+		sub, ok := lookupCommand(args[0])
+		if !ok {
+			fmt.Fprintf(stderr, "Error: unknown command %q\n", args[0])
+			return exitUsage
+		}
+		cmd = sub
+	}
+
+	_, err := parseArgs(cmd, []string{"-h"}, stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	return exitUsage
+}
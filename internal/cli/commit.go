@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/arrno/bfast/internal/git"
+)
+
+const defaultCommitMessage = "Add blazingly fast badge"
+
+var errCommitOutsideRepo = errors.New("--commit needs the README to be inside a git repository")
+
+// checkCommit runs before anything is written so that --commit never leaves
+// a modified README behind a failed precondition.
+func checkCommit(ctx context.Context, opts *options, tgt *target) error {
+	if !opts.commit {
+		return nil
+	}
+	if tgt.root == "" {
+		return errCommitOutsideRepo
+	}
+	return git.CheckIndexClean(ctx, tgt.root)
+}
+
+// commitReadme records the README change as a commit and stores its hash in
+// the result.
+func commitReadme(ctx context.Context, opts *options, tgt *target, file *readmeFile, res *result) error {
+	if !opts.commit {
+		return nil
+	}
+
+	hash, err := git.CommitFile(ctx, tgt.root, file.path, commitMessage(opts, tgt, res))
+	if err != nil {
+		return err
+	}
+	res.Commit = hash
+	return nil
+}
+
+// commitMessage expands the --commit-message template, or the default
+// message.
+func commitMessage(opts *options, tgt *target, res *result) string {
+	template := opts.commitMessage
+	if strings.TrimSpace(template) == "" {
+		template = defaultCommitMessage
+	}
+	return expandTemplate(template, tgt, res)
+}
+
+// expandTemplate replaces {repo}, {blurb}, and {readme} in a message
+// template.
+func expandTemplate(template string, tgt *target, res *result) string {
+	return strings.NewReplacer(
+		"{repo}", res.Repo,
+		"{blurb}", res.Blurb,
+		"{readme}", readmeName(tgt, res.Readme),
+	).Replace(template)
+}
+
+// readmeName is the README path relative to the git root.
+func readmeName(tgt *target, path string) string {
+	if rel, err := filepath.Rel(tgt.root, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
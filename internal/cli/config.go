@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/arrno/bfast/internal/config"
+)
+
+const redacted = "********"
+
+// runConfig reads and writes the user config file. Tokens are redacted by
+// list but printed by get so they can be scripted.
+func runConfig(ctx context.Context, opts *options, stdout, stderr io.Writer) error {
+	if len(opts.args) == 0 {
+		return errors.New("missing action. Use: bfast config get|set|list|path")
+	}
+
+	action, args := opts.args[0], opts.args[1:]
+	switch action {
+	case "path":
+		if len(args) != 0 {
+			return errors.New("usage: bfast config path")
+		}
+		path, err := config.UserPath()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, path)
+		return nil
+	case "list":
+		if len(args) != 0 {
+			return errors.New("usage: bfast config list")
+		}
+		file, err := config.LoadUser()
+		if err != nil {
+			return err
+		}
+		entries := file.Entries()
+		for i := range entries {
+			if isSecretKey(entries[i].Key) {
+				entries[i].Value = redacted
+			}
+		}
+		if opts.json {
+			if entries == nil {
+				entries = []config.Entry{}
+			}
+			emitJSON(entries, stdout)
+			return nil
+		}
+		for _, entry := range entries {
+			fmt.Fprintf(stdout, "%s = %s\n", entry.Key, entry.Value)
+		}
+		return nil
+	case "get":
+		if len(args) != 1 {
+			return errors.New("usage: bfast config get <key>")
+		}
+		if err := config.ValidateUserKey(args[0]); err != nil {
+			return err
+		}
+		file, err := config.LoadUser()
+		if err != nil {
+			return err
+		}
+		value, ok := file.Get(args[0])
+		if !ok {
+			return fmt.Errorf("%s is not set in %s", args[0], file.Path)
+		}
+		if opts.json {
+			emitJSON(config.Entry{Key: args[0], Value: value}, stdout)
+			return nil
+		}
+		fmt.Fprintln(stdout, value)
+		return nil
+	case "set":
+		if len(args) != 2 {
+			return errors.New("usage: bfast config set <key> <value>")
+		}
+		path, err := config.UserPath()
+		if err != nil {
+			return err
+		}
+		if err := config.SetUserValue(path, args[0], args[1]); err != nil {
+			return err
+		}
+		if opts.json {
+			emitJSON(config.Entry{Key: args[0], Value: args[1]}, stdout)
+			return nil
+		}
+		fmt.Fprintf(stdout, "Set %s in %s\n", args[0], path)
+		return nil
+	default:
+		return fmt.Errorf("unknown config action %q. Use: bfast config get|set|list|path", action)
+	}
+}
+
+func isSecretKey(key string) bool {
+	return strings.HasPrefix(key, "profile.") && strings.HasSuffix(key, "."+config.ProfileToken)
+}
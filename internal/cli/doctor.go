@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+
+	"github.com/arrno/bfast/internal/api"
+	"github.com/arrno/bfast/internal/config"
+	"github.com/arrno/bfast/internal/git"
+	"github.com/arrno/bfast/internal/readme"
+)
+
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+type check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+type doctorResult struct {
+	OK     bool    `json:"ok"`
+	Checks []check `json:"checks"`
+}
+
+// runDoctor inspects the environment bfast depends on and reports each
+// finding. It exits non-zero when any check fails.
+func runDoctor(ctx context.Context, opts *options, stdout, stderr io.Writer) error {
+	res := &doctorResult{OK: true}
+	add := func(name, status, detail string) {
+		if status == checkFail {
+			res.OK = false
+		}
+		res.Checks = append(res.Checks, check{Name: name, Status: status, Detail: detail})
+	}
+
+	if path, err := exec.LookPath("git"); err != nil {
+		add("git", checkWarn, "git binary not found on PATH; only needed for --commit, --pr, and --verify")
+	} else {
+		add("git", checkOK, path)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	root, err := git.FindRepoRoot(cwd)
+	if err != nil {
+		add("repository", checkFail, err.Error())
+		root = ""
+	} else {
+		add("repository", checkOK, root)
+	}
+
+	if root == "" {
+		add("config", checkSkip, "no repository to inspect")
+	} else if file, err := config.LoadRepo(root); err != nil {
+		add("config", checkFail, err.Error())
+	} else if file == nil {
+		add("config", checkOK, "no repo config file")
+	} else {
+		add("config", checkOK, file.Path)
+	}
+
+	// Settings are resolved before the remote check so that repo, remote,
+	// and forges from the config files apply to it.
+	settingsErr := applySettings(opts, root)
+
+	switch {
+	case opts.repoInput != "":
+		if slug, err := opts.forges.Parse(opts.repoInput); err != nil {
+			add("remote", checkFail, err.Error())
+		} else {
+			add("remote", checkOK, slug.String()+" (from --repo)")
+		}
+	case root == "":
+		add("remote", checkSkip, "no repository to inspect")
+	default:
+		if d, err := git.DetectRepo(ctx, root, git.RemoteOptions{Name: opts.remote, Preference: opts.remotes, Hosts: opts.forges}); err != nil {
+			add("remote", checkFail, err.Error())
+		} else {
+			add("remote", checkOK, fmt.Sprintf("%s (remote %s)", d.Slug, d.Remote))
+			if parent, name, ok := d.LikelyForkOf(); ok && opts.remote == "" {
+				add("fork", checkWarn, fmt.Sprintf("%s looks like a fork: remote %s points at %s", d.Slug, name, parent))
+			}
+		}
+	}
+
+	readmePath, err := resolveReadmePath(root, cwd, opts.readmeInput)
+	if err != nil {
+		add("readme", checkFail, err.Error())
+	} else if content, err := os.ReadFile(readmePath); err != nil {
+		add("readme", checkFail, err.Error())
+	} else {
+		add("readme", checkOK, readmePath)
+		if readme.HasBadge(string(content)) {
+			add("badge", checkOK, "README already badged")
+		} else {
+			add("badge", checkWarn, "README not badged yet")
+		}
+	}
+
+	if settingsErr != nil {
+		add("api", checkFail, settingsErr.Error())
+	} else if base := opts.api.baseURL; base == "" {
+		add("api", checkOK, api.DefaultBaseURL+profileNote(opts))
+	} else if parsed, err := url.Parse(base); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		add("api", checkFail, fmt.Sprintf("base URL from %s is not an http(s) URL: %q", opts.sources["api"], base))
+	} else {
+		add("api", checkOK, base+" (from "+opts.sources["api"]+")"+profileNote(opts))
+	}
+
+	if settingsErr != nil {
+		add("network", checkSkip, "API settings could not be resolved")
+	} else {
+		add("network", checkOK, networkSummary(opts.api))
+	}
+
+	switch {
+	case settingsErr != nil:
+		add("auth", checkSkip, "API settings could not be resolved")
+	case opts.api.token == "":
+		add("auth", checkWarn, "no token; run \"bfast login\" to manage your entries")
+	default:
+		add("auth", checkOK, "token from "+opts.sources["token"])
+	}
+
+	if opts.json {
+		emitJSON(res, stdout)
+	} else {
+		for _, c := range res.Checks {
+			fmt.Fprintf(stdout, "%-5s %-11s %s\n", c.Status, c.Name, c.Detail)
+		}
+	}
+
+	if !res.OK {
+		return &exitError{code: exitFailure}
+	}
+	return nil
+}
+
+func profileNote(opts *options) string {
+	if opts.profile == "" {
+		return ""
+	}
+	return " [profile " + opts.profile + "]"
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/arrno/bfast/internal/api"
+	"github.com/arrno/bfast/internal/blurb"
+	"github.com/arrno/bfast/internal/git"
+	"github.com/arrno/bfast/internal/normalize"
+	"github.com/arrno/bfast/internal/readme"
+)
+
+// Exit codes are part of the CLI contract and documented in the README.
+// Never renumber them; add new classes at the end.
+const (
+	exitOK             = 0  // success
+	exitFailure        = 1  // any failure without a more specific code
+	exitUsage          = 2  // invalid flags or arguments
+	exitCheckFailed    = 3  // bfast check found problems
+	exitNotRepository  = 4  // not inside a git repository
+	exitRepoDetection  = 5  // no forge remote, or more than one
+	exitInvalidRepo    = 6  // --repo or positional repo could not be parsed
+	exitReadmeNotFound = 7  // README missing
+	exitWriteFailed    = 8  // README could not be written
+	exitAPIRejected    = 9  // API answered with a 4xx
+	exitAPIUnavailable = 10 // API answered with a 5xx, or could not be reached
+)
+
+// Error codes in the JSON error payload. Like exit codes, they are part of
+// the CLI contract.
+const (
+	codeUsage          = "usage"
+	codeError          = "error"
+	codeNotRepository  = "not_repository"
+	codeNoRemote       = "no_github_remote"
+	codeAmbiguous      = "ambiguous_remote"
+	codeInvalidRepo    = "invalid_repo"
+	codeReadmeNotFound = "readme_not_found"
+	codeWriteFailed    = "write_failed"
+	codeBlurbInvalid   = "blurb_invalid"
+	codeDirtyIndex     = "dirty_index"
+	codeUnmergedFiles  = "unmerged_files"
+	codeAPIConflict    = "api_conflict"
+	codeAPINotFound    = "api_not_found"
+	codeAPIRejected    = "api_rejected"
+	codeAPIError       = "api_error"
+	codeAPIUnreachable = "api_unreachable"
+	codeUnverified     = "verification_failed"
+	codeNoSuchRemote   = "remote_not_found"
+	codeForkRefused    = "fork_refused"
+)
+
+var errReadmeWrite = errors.New("failed to update README")
+
+// errorClasses maps sentinel errors to their exit and error codes, checked in
+// order with errors.Is.
+var errorClasses = []struct {
+	target error
+	exit   int
+	code   string
+}{
+	{git.ErrNotRepository, exitNotRepository, codeNotRepository},
+	{git.ErrAmbiguousRepo, exitRepoDetection, codeAmbiguous},
+	{git.ErrNoRepoRemote, exitRepoDetection, codeNoRemote},
+	{git.ErrRemoteNotFound, exitRepoDetection, codeNoSuchRemote},
+	{errForkRefused, exitRepoDetection, codeForkRefused},
+	{normalize.ErrInvalidRepo, exitInvalidRepo, codeInvalidRepo},
+	{readme.ErrNotFound, exitReadmeNotFound, codeReadmeNotFound},
+	{errReadmeWrite, exitWriteFailed, codeWriteFailed},
+	{blurb.ErrInvalidBlurb, exitFailure, codeBlurbInvalid},
+	{git.ErrDirtyIndex, exitFailure, codeDirtyIndex},
+	{git.ErrUnmergedFiles, exitFailure, codeUnmergedFiles},
+	{api.ErrAlreadyRegistered, exitAPIRejected, codeAPIConflict},
+	{api.ErrNotRegistered, exitAPIRejected, codeAPINotFound},
+	{api.ErrUnreachable, exitAPIUnavailable, codeAPIUnreachable},
+	{errVerificationFailed, exitAPIRejected, codeUnverified},
+}
+
+// errorPayload is the --json error report. Only the fields relevant to the
+// error are set.
+type errorPayload struct {
+	Error         string   `json:"error"`
+	Code          string   `json:"code"`
+	ExitCode      int      `json:"exitCode"`
+	Status        int      `json:"status,omitempty"`
+	ServerMessage string   `json:"serverMessage,omitempty"`
+	Candidates    []string `json:"candidates,omitempty"`
+	Path          string   `json:"path,omitempty"`
+}
+
+// fileError records the file an error is about, for the JSON payload.
+type fileError struct {
+	path string
+	err  error
+}
+
+func (e *fileError) Error() string { return e.err.Error() }
+
+func (e *fileError) Unwrap() error { return e.err }
+
+// hintError replaces the message of err with one that suggests a fix, while
+// keeping err for classification.
+type hintError struct {
+	msg string
+	err error
+}
+
+func (e *hintError) Error() string { return e.msg }
+
+func (e *hintError) Unwrap() error { return e.err }
+
+// exitCode maps an execution error to its documented exit code.
+func exitCode(err error) int {
+	code, _ := classifyError(err)
+	return code
+}
+
+// classifyError returns the exit code and error code for err.
+func classifyError(err error) (int, string) {
+	var exit *exitError
+	if err == nil {
+		return exitOK, ""
+	}
+	if errors.As(err, &exit) {
+		return exit.code, codeError
+	}
+	for _, class := range errorClasses {
+		if errors.Is(err, class.target) {
+			return class.exit, class.code
+		}
+	}
+
+	var apiErr *api.Error
+	if errors.As(err, &apiErr) {
+		if apiErr.Status >= 500 {
+			return exitAPIUnavailable, codeAPIError
+		}
+		return exitAPIRejected, codeAPIRejected
+	}
+	return exitFailure, codeError
+}
+
+// describeError builds the JSON error payload for err, which exited with
+// exit.
+func describeError(err error, exit int) errorPayload {
+	payload := errorPayload{Error: err.Error(), ExitCode: exit}
+	if exit == exitUsage {
+		payload.Code = codeUsage
+	} else {
+		_, payload.Code = classifyError(err)
+	}
+
+	var apiErr *api.Error
+	if errors.As(err, &apiErr) {
+		payload.Status = apiErr.Status
+		payload.ServerMessage = apiErr.Message
+	}
+
+	var ambiguous *git.AmbiguousRepoError
+	if errors.As(err, &ambiguous) {
+		payload.Candidates = ambiguous.Candidates
+	}
+
+	var fileErr *fileError
+	var pathErr *fs.PathError
+	switch {
+	case errors.As(err, &fileErr):
+		payload.Path = fileErr.path
+	case errors.As(err, &pathErr):
+		payload.Path = pathErr.Path
+	}
+	return payload
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/arrno/bfast/internal/github"
+	"github.com/arrno/bfast/internal/normalize"
+)
+
+// Values of --fork-check.
+const (
+	forkCheckWarn   = "warn"
+	forkCheckRefuse = "refuse"
+	forkCheckOff    = "off"
+)
+
+var errForkRefused = errors.New("repository looks like a fork")
+
+// parseForkCheck validates a --fork-check value; empty means warn.
+func parseForkCheck(value string) (string, error) {
+	switch value = strings.ToLower(strings.TrimSpace(value)); value {
+	case "":
+		return forkCheckWarn, nil
+	case forkCheckWarn, forkCheckRefuse, forkCheckOff:
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid fork check %q (use warn, refuse, or off)", value)
+}
+
+// checkFork warns about, or with --fork-check refuse rejects, a target that
+// looks like a fork, so the original project gets the badge instead. The
+// local heuristic only applies when the remote was picked automatically;
+// with --fork-api, GitHub's answer overrides it.
+func checkFork(ctx context.Context, opts *options, tgt *target, res *result) error {
+	if opts.forkCheck == forkCheckOff {
+		return nil
+	}
+
+	var reason string
+	if tgt.detection != nil && opts.remote == "" {
+		if parent, name, ok := tgt.detection.LikelyForkOf(); ok {
+			res.ForkOf = parent.String()
+			reason = fmt.Sprintf("remote %s points at %s", name, parent)
+		}
+	}
+
+	switch {
+	case opts.forkAPI && tgt.slug.Forge != normalize.GitHub:
+		res.warn("--fork-api only asks GitHub; skipped for %s", tgt.slug)
+	case opts.forkAPI:
+		client := github.NewClient(githubBaseURL(), githubToken(), githubHTTPClient(opts))
+		repo, err := client.GetRepository(ctx, tgt.slug.Owner, tgt.slug.Repo)
+		switch {
+		case err != nil:
+			res.warn("could not check with GitHub whether %s is a fork: %s", tgt.slug, err)
+		case repo.Fork:
+			res.ForkOf = ""
+			reason = "GitHub lists it as a fork"
+			if repo.Parent != nil {
+				res.ForkOf = repo.Parent.FullName
+				reason = "GitHub lists it as a fork of " + repo.Parent.FullName
+			}
+		default:
+			res.ForkOf = ""
+			reason = ""
+		}
+	}
+
+	if reason == "" {
+		return nil
+	}
+	if opts.forkCheck == forkCheckRefuse {
+		msg := fmt.Sprintf("%s looks like a fork (%s). Use --remote or --repo to target the original, or --fork-check warn to continue", tgt.slug, reason)
+		return &hintError{msg: msg, err: errForkRefused}
+	}
+	res.warn("%s looks like a fork (%s). Use --remote or --repo to target the original.", tgt.slug, reason)
+	return nil
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Log formats accepted by --log-format.
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// defaultLogLevel keeps the internal debug logging quiet unless asked for.
+const defaultLogLevel = slog.LevelWarn
+
+// newLogger builds the logger selected by -v, --log-level, and --log-format.
+// Logs always go to stderr so they never mix with --json output.
+func newLogger(opts *options, stderr io.Writer) (*slog.Logger, error) {
+	level := defaultLogLevel
+	switch {
+	case opts.logLevel != "":
+		if err := level.UnmarshalText([]byte(opts.logLevel)); err != nil {
+			return nil, fmt.Errorf("unknown log level %q (want debug, info, warn, or error)", opts.logLevel)
+		}
+	case opts.verbose:
+		level = slog.LevelDebug
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(opts.logFormat) {
+	case "", logFormatText:
+		return slog.New(slog.NewTextHandler(stderr, handlerOpts)), nil
+	case logFormatJSON:
+		return slog.New(slog.NewJSONHandler(stderr, handlerOpts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (want text or json)", opts.logFormat)
+	}
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arrno/bfast/internal/api"
+	"github.com/arrno/bfast/internal/config"
+)
+
+// stdin is read by bfast login --with-token. Tests replace it.
+var stdin io.Reader = os.Stdin
+
+var errEmptyToken = errors.New("no token provided on stdin")
+
+type loginResult struct {
+	Profile     string   `json:"profile"`
+	Method      string   `json:"method"`
+	Credentials string   `json:"credentials"`
+	LoggedOut   bool     `json:"loggedOut,omitempty"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// runLogin stores an API token for the selected profile, obtained through
+// the device login flow or read from stdin with --with-token.
+func runLogin(ctx context.Context, opts *options, stdout, stderr io.Writer) error {
+	if err := applySettings(opts, ""); err != nil {
+		return err
+	}
+
+	res := &loginResult{Profile: credentialName(opts)}
+	var token string
+	var err error
+	if opts.withToken {
+		res.Method = "token"
+		token, err = readToken(stdin, stderr)
+	} else {
+		res.Method = "device"
+		token, err = deviceLogin(ctx, opts, stderr)
+	}
+	if err != nil {
+		return err
+	}
+
+	cred := config.Credential{Token: token, BaseURL: opts.api.baseURL, CreatedAt: time.Now().UTC()}
+	if err := config.SaveCredential(res.Profile, cred); err != nil {
+		return err
+	}
+	if res.Credentials, err = config.CredentialsPath(); err != nil {
+		return err
+	}
+	if os.Getenv(tokenEnv) != "" {
+		res.Warnings = append(res.Warnings, tokenEnv+" is set and overrides the stored token.")
+	}
+
+	if opts.json {
+		emitJSON(res, stdout)
+		return nil
+	}
+	for _, msg := range res.Warnings {
+		fmt.Fprintf(stderr, "Warning: %s\n", msg)
+	}
+	fmt.Fprintf(stdout, "Logged in (profile %s). Token saved to %s\n", res.Profile, res.Credentials)
+	return nil
+}
+
+// runLogout deletes the stored token for the selected profile.
+func runLogout(ctx context.Context, opts *options, stdout, stderr io.Writer) error {
+	if err := applySettings(opts, ""); err != nil {
+		return err
+	}
+
+	res := &loginResult{Profile: credentialName(opts)}
+	removed, err := config.DeleteCredential(res.Profile)
+	if err != nil {
+		return err
+	}
+	res.LoggedOut = removed
+	if res.Credentials, err = config.CredentialsPath(); err != nil {
+		return err
+	}
+
+	if opts.json {
+		emitJSON(res, stdout)
+		return nil
+	}
+	if removed {
+		fmt.Fprintf(stdout, "Logged out (profile %s).\n", res.Profile)
+	} else {
+		fmt.Fprintf(stdout, "Not logged in (profile %s).\n", res.Profile)
+	}
+	return nil
+}
+
+// deviceLogin runs the device authorization flow against the auth URL. The
+// code to enter is printed even with --json, since a person has to act on
+// it.
+func deviceLogin(ctx context.Context, opts *options, stderr io.Writer) (string, error) {
+	client := api.New(api.Config{
+		BaseURL:   opts.api.authURL,
+		Timeout:   opts.api.timeout,
+		Transport: opts.roundTripper(),
+	})
+
+	code, err := client.RequestDeviceCode(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	uri := code.VerificationURIComplete
+	if uri == "" {
+		uri = code.VerificationURI
+	}
+	fmt.Fprintf(stderr, "Open %s and enter the code %s\nWaiting for approval...\n", uri, code.UserCode)
+
+	return client.PollDeviceToken(ctx, code)
+}
+
+// readToken reads a pasted token from the first line of r, prompting when r
+// is a terminal.
+func readToken(r io.Reader, stderr io.Writer) (string, error) {
+	if w, ok := r.(io.Writer); ok && isTerminal(w) {
+		fmt.Fprint(stderr, "Paste your token: ")
+	}
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read token: %w", err)
+	}
+	token := strings.TrimSpace(line)
+	if token == "" {
+		return "", errEmptyToken
+	}
+	return token, nil
+}
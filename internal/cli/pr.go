@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arrno/bfast/internal/git"
+	"github.com/arrno/bfast/internal/github"
+	"github.com/arrno/bfast/internal/normalize"
+)
+
+const (
+	defaultPRBranch = "bfast/badge"
+	defaultPRTitle  = "Add blazingly fast badge: {blurb}"
+	githubAPIEnv    = "BFAST_GITHUB_API_URL"
+)
+
+// githubTokenEnvs are checked in order for the token used to open the pull
+// request.
+var githubTokenEnvs = []string{"BFAST_GITHUB_TOKEN", "GITHUB_TOKEN", "GH_TOKEN"}
+
+var errPRToken = errors.New("--pr needs a GitHub token in BFAST_GITHUB_TOKEN, GITHUB_TOKEN, or GH_TOKEN")
+
+// pullRequest carries the branch plan for --pr between the steps of a run.
+type pullRequest struct {
+	client *github.Client
+	start  string
+	base   string
+	branch string
+	// remote is the git remote the branch is pushed to, and head names the
+	// branch for the pull request: owner:branch when remote is a fork.
+	remote string
+	head   string
+}
+
+// preparePR validates --pr before anything is written or registered, and
+// returns nil when --pr was not requested.
+func preparePR(ctx context.Context, opts *options, tgt *target) (*pullRequest, error) {
+	if !opts.pr {
+		return nil, nil
+	}
+
+	if tgt.slug.Forge != normalize.GitHub {
+		return nil, fmt.Errorf("--pr only supports GitHub repositories, and %s is on %s", tgt.slug, tgt.slug.Host)
+	}
+
+	token := githubToken()
+	if token == "" {
+		return nil, errPRToken
+	}
+
+	start, err := git.CurrentBranch(ctx, tgt.root)
+	if err != nil {
+		return nil, err
+	}
+
+	pr := &pullRequest{
+		client: github.NewClient(githubBaseURL(), token, githubHTTPClient(opts)),
+		start:  start,
+		base:   opts.prBase,
+		branch: opts.prBranch,
+	}
+	if pr.base == "" {
+		pr.base = start
+	}
+	if pr.branch == "" {
+		pr.branch = defaultPRBranch
+	}
+	if pr.branch == pr.base {
+		return nil, fmt.Errorf("--pr-branch %s is the same as the base branch", pr.branch)
+	}
+
+	push, err := pushRemote(ctx, opts, tgt)
+	if err != nil {
+		return nil, err
+	}
+	if push.Slug.Host != tgt.slug.Host {
+		return nil, fmt.Errorf("--pr pushes to remote %s, which is on %s, not %s; pick a remote on %s with --remote", push.Remote, push.Slug.Host, tgt.slug.Host, tgt.slug.Host)
+	}
+	pr.remote = push.Remote
+	pr.head = pr.branch
+	if !sameRepo(push.Slug, tgt.slug) {
+		pr.head = push.Slug.Owner + ":" + pr.branch
+	}
+
+	exists, err := git.BranchExists(ctx, tgt.root, pr.branch)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("branch %s already exists; delete it or pick another with --pr-branch", pr.branch)
+	}
+	return pr, nil
+}
+
+// pushRemote returns the remote that --pr and --verify push to, with the
+// repository it points at: the remote the target was detected from, or with
+// --repo, the remote detection would pick in the checkout.
+func pushRemote(ctx context.Context, opts *options, tgt *target) (*git.Detection, error) {
+	if tgt.detection != nil {
+		return tgt.detection, nil
+	}
+	push, err := git.DetectRepo(ctx, tgt.root, git.RemoteOptions{Name: opts.remote, Preference: opts.remotes, Hosts: opts.forges})
+	if err != nil {
+		return nil, fmt.Errorf("no git remote to push to: %w", err)
+	}
+	return push, nil
+}
+
+// sameRepo reports whether a and b name the same repository; forges match
+// owner and repository names case-insensitively.
+func sameRepo(a, b normalize.Slug) bool {
+	return strings.EqualFold(a.Host, b.Host) && strings.EqualFold(a.Owner, b.Owner) && strings.EqualFold(a.Repo, b.Repo)
+}
+
+// branchOff creates and checks out the pull request branch so the README
+// commit lands there instead of on the base branch.
+func (p *pullRequest) branchOff(ctx context.Context, tgt *target) error {
+	return git.CreateBranch(ctx, tgt.root, p.branch)
+}
+
+// abandon handles a failure after branchOff: it checks out the branch the
+// run started on and deletes the pull request branch unless the README
+// commit already landed there. It returns cause, noting anything left
+// behind. A nil pullRequest returns cause unchanged.
+func (p *pullRequest) abandon(ctx context.Context, tgt *target, res *result, cause error) error {
+	if p == nil {
+		return cause
+	}
+	if err := git.Checkout(ctx, tgt.root, p.start); err != nil {
+		return fmt.Errorf("%w (still on branch %s: %v)", cause, p.branch, err)
+	}
+	if res.Commit != "" {
+		return fmt.Errorf("%w (the README commit is kept on branch %s)", cause, p.branch)
+	}
+	if err := git.DeleteBranch(ctx, tgt.root, p.branch); err != nil {
+		return fmt.Errorf("%w (%v)", cause, err)
+	}
+	return cause
+}
+
+// open pushes the branch, returns the checkout to the branch the run started
+// on, and opens the pull request against the base branch.
+func (p *pullRequest) open(ctx context.Context, tgt *target, res *result) error {
+	if err := git.Push(ctx, tgt.root, p.remote, p.branch); err != nil {
+		return err
+	}
+	res.Branch = p.branch
+
+	if err := git.Checkout(ctx, tgt.root, p.start); err != nil {
+		return err
+	}
+
+	created, err := p.client.CreatePullRequest(ctx, tgt.slug.Owner, tgt.slug.Repo, github.NewPullRequest{
+		Title: expandTemplate(defaultPRTitle, tgt, res),
+		Body:  prBody(tgt, res),
+		Head:  p.head,
+		Base:  p.base,
+	})
+	if err != nil {
+		return fmt.Errorf("pushed %s but could not open a pull request: %w", p.branch, err)
+	}
+	res.PullRequest = created.HTMLURL
+	return nil
+}
+
+func prBody(tgt *target, res *result) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Adds the [blazingly.fast](%s) badge to `%s`.\n\n", res.BadgeDestination, readmeName(tgt, res.Readme))
+	fmt.Fprintf(&b, "> %s\n", res.Blurb)
+	if res.Registered || res.AlreadyRegistered {
+		fmt.Fprintf(&b, "\n%s is registered at blazingly.fast.\n", res.Repo)
+	}
+	return b.String()
+}
+
+func githubToken() string {
+	for _, name := range githubTokenEnvs {
+		if token := strings.TrimSpace(os.Getenv(name)); token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+// githubBaseURL honors BFAST_GITHUB_API_URL, then the GITHUB_API_URL that
+// GitHub Actions sets, and otherwise uses the public API.
+func githubBaseURL() string {
+	for _, name := range []string{githubAPIEnv, "GITHUB_API_URL"} {
+		if base := strings.TrimSpace(os.Getenv(name)); base != "" {
+			return base
+		}
+	}
+	return github.DefaultBaseURL
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// runRegister submits the repo to the API without reading or writing the README.
+func runRegister(ctx context.Context, opts *options, stdout, stderr io.Writer) error {
+	tgt, err := resolveTarget(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	res := newResult(tgt, nil, opts)
+	if err := checkFork(ctx, opts, tgt, res); err != nil {
+		return err
+	}
+	if err := chooseBlurb(opts, res); err != nil {
+		return err
+	}
+
+	if !opts.dryRun {
+		if err := checkVerify(ctx, opts, tgt); err != nil {
+			return err
+		}
+		client := newAPIClient(opts)
+		challenge, err := verifyOwnership(ctx, client, opts, tgt, res)
+		if err != nil {
+			return err
+		}
+		if err := registerRepo(ctx, client, tgt.slug, challenge, opts, res); err != nil {
+			return err
+		}
+	}
+
+	if opts.json {
+		emitJSON(res, stdout)
+		return nil
+	}
+
+	printDiagnostics(stderr, res)
+
+	switch {
+	case res.DryRun:
+		fmt.Fprintf(stdout, "Dry run: would register %s with blurb: \"%s\"\n", res.Repo, res.Blurb)
+	case res.AlreadyRegistered:
+		fmt.Fprintf(stdout, "Repo %s already registered.\n", res.Repo)
+		printMismatch(stdout, res)
+	default:
+		fmt.Fprintf(stdout, "Registered %s with blurb: \"%s\"\n", res.Repo, res.Blurb)
+	}
+	printVerification(stdout, res)
+	return nil
+}
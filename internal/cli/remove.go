@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/arrno/bfast/internal/readme"
+)
+
+type removeResult struct {
+	Readme  string `json:"readme"`
+	Removed int    `json:"removed"`
+	DryRun  bool   `json:"dryRun"`
+	Diff    string `json:"diff,omitempty"`
+}
+
+// runRemove strips every blazingly.fast badge from the README. It needs no
+// repo slug, so it works even when remotes cannot be inferred.
+func runRemove(ctx context.Context, opts *options, stdout, stderr io.Writer) error {
+	tgt, err := locateRepo(opts)
+	if err != nil {
+		return err
+	}
+
+	file, err := loadReadme(tgt, opts.readmeInput)
+	if err != nil {
+		return err
+	}
+
+	updated, removed := readme.RemoveBadge(file.content)
+	res := &removeResult{Readme: file.path, Removed: removed, DryRun: opts.dryRun}
+
+	switch {
+	case removed == 0:
+	case opts.dryRun:
+		res.Diff = readmeDiff(tgt, file, updated)
+	default:
+		if err := file.write(updated); err != nil {
+			return err
+		}
+	}
+
+	if opts.json {
+		emitJSON(res, stdout)
+		return nil
+	}
+
+	switch {
+	case res.Removed == 0:
+		fmt.Fprintln(stdout, "No badge found. No changes.")
+	case res.DryRun:
+		fmt.Fprintf(stdout, "Dry run: would remove %s from %s\n", pluralBadges(res.Removed), res.Readme)
+		printDiff(stdout, res.Diff)
+	default:
+		fmt.Fprintf(stdout, "Removed %s from %s\n", pluralBadges(res.Removed), res.Readme)
+	}
+	return nil
+}
+
+func pluralBadges(n int) string {
+	if n == 1 {
+		return "1 badge"
+	}
+	return fmt.Sprintf("%d badges", n)
+}
@@ -0,0 +1,394 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arrno/bfast/internal/api"
+	"github.com/arrno/bfast/internal/config"
+	"github.com/arrno/bfast/internal/normalize"
+	"github.com/arrno/bfast/internal/readme"
+)
+
+const (
+	sourceFlag = "flag"
+	profileEnv = "BFAST_PROFILE"
+	tokenEnv   = "BFAST_TOKEN"
+	authURLEnv = "BFAST_AUTH_URL"
+)
+
+// settingEnv maps each config key to the environment variable that overrides it.
+var settingEnv = map[string]string{
+	config.KeyBlurb:     "BFAST_BLURB",
+	config.KeyHidden:    "BFAST_HIDDEN",
+	config.KeyReadme:    "BFAST_README",
+	config.KeyRepo:      "BFAST_REPO",
+	config.KeyPlacement: "BFAST_PLACEMENT",
+	config.KeyRemote:    "BFAST_REMOTE",
+	config.KeyRemotes:   "BFAST_REMOTES",
+	config.KeyForkCheck: "BFAST_FORK_CHECK",
+	config.KeyForkAPI:   "BFAST_FORK_API",
+	config.KeyForges:    "BFAST_FORGES",
+}
+
+// settingLayer is one source of setting values, named for reporting.
+type settingLayer struct {
+	source string
+	values map[string]string
+	// repo marks the checked-in repo config, which is untrusted: a cloned
+	// repository must not point bfast at files outside it.
+	repo bool
+}
+
+var errReadmeOutsideRepo = errors.New("readme must be a path inside the repository")
+
+// apiSettings is the resolved API profile used to build the client.
+type apiSettings struct {
+	baseURL string
+	authURL string
+	token   string
+	timeout time.Duration
+	retries int // -1 means the client default
+
+	caCert     string
+	clientCert string
+	clientKey  string
+	proxy      string
+	transport  *http.Transport // nil means the default transport
+}
+
+// applySettings fills options the user did not pass as flags from, in order,
+// the environment, the repo config file at root, and the user config. It
+// also resolves the API profile, and records where each setting came from in
+// opts.sources.
+func applySettings(opts *options, root string) error {
+	user, err := config.LoadUser()
+	if err != nil {
+		return err
+	}
+
+	layers := []settingLayer{envLayer()}
+	if root != "" {
+		file, err := config.LoadRepo(root)
+		if err != nil {
+			return err
+		}
+		if file != nil {
+			layers = append(layers, settingLayer{source: file.Path, values: file.Values, repo: true})
+		}
+	}
+	layers = append(layers, settingLayer{source: user.Path, values: user.Values})
+
+	// --remote and a repo both pick the target, so a --remote flag also
+	// shadows a repo from the environment or config files.
+	remoteFlag := flagProvided(opts, config.KeyRemote)
+
+	opts.sources = map[string]string{}
+	for _, key := range config.Keys {
+		if flagProvided(opts, key) {
+			opts.sources[key] = sourceFlag
+			continue
+		}
+		if key == config.KeyRepo && remoteFlag {
+			continue
+		}
+		for _, layer := range layers {
+			value, ok := layer.values[key]
+			if !ok {
+				continue
+			}
+			if layer.repo && key == config.KeyReadme {
+				var err error
+				if value, err = repoReadmePath(root, value); err != nil {
+					return fmt.Errorf("%s from %s: %w", key, layer.source, err)
+				}
+			}
+			if err := setOption(opts, key, value); err != nil {
+				return fmt.Errorf("%s from %s: %w", key, layer.source, err)
+			}
+			opts.sources[key] = layer.source
+			break
+		}
+	}
+
+	for key, source := range opts.sources {
+		slog.Debug("resolved setting", "key", key, "source", source)
+	}
+
+	placement, err := readme.ParsePlacement(opts.placement)
+	if err != nil {
+		return err
+	}
+	opts.placement = string(placement)
+
+	if opts.forkCheck, err = parseForkCheck(opts.forkCheck); err != nil {
+		return err
+	}
+
+	return applyProfile(opts, user)
+}
+
+// applyProfile selects the API profile named by --profile, BFAST_PROFILE, or
+// the user config default, and resolves the client settings from it.
+// BFAST_API_BASE_URL and BFAST_AUTH_URL override the profile's URLs unless
+// the profile was chosen with --profile.
+func applyProfile(opts *options, user *config.File) error {
+	name, source := opts.profile, sourceFlag
+	if name == "" {
+		name, source = strings.TrimSpace(os.Getenv(profileEnv)), "env"
+	}
+	if name == "" {
+		name, source = user.Values[config.KeyProfile], user.Path
+	}
+
+	fields := map[string]string{}
+	if name != "" {
+		profile, ok := user.Profiles[name]
+		if !ok {
+			return fmt.Errorf("unknown profile %q; define [profile.%s] in %s", name, name, user.Path)
+		}
+		fields = profile
+		opts.profile = name
+		opts.sources[config.KeyProfile] = source
+	}
+
+	// A profile picked with --profile is a flag, so its URLs win over the
+	// environment; one picked otherwise does not.
+	profileFlag := name != "" && source == sourceFlag
+	profileSource := "profile " + name
+	opts.api = apiSettings{}
+	envBase := strings.TrimSpace(os.Getenv(apiBaseEnv))
+	switch base := fields[config.ProfileBaseURL]; {
+	case base != "" && (profileFlag || envBase == ""):
+		opts.api.baseURL = base
+		opts.sources["api"] = profileSource
+	case envBase != "":
+		opts.api.baseURL = envBase
+		opts.sources["api"] = "env"
+	}
+
+	if opts.timeout > 0 {
+		opts.api.timeout = opts.timeout
+	} else if raw := fields[config.ProfileTimeout]; raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("%s: invalid timeout %q", profileSource, raw)
+		}
+		opts.api.timeout = timeout
+	}
+
+	opts.api.retries = -1
+	if opts.retriesProvided {
+		opts.api.retries = opts.retries
+	} else if raw := fields[config.ProfileRetries]; raw != "" {
+		retries, err := strconv.Atoi(raw)
+		if err != nil || retries < 0 {
+			return fmt.Errorf("%s: invalid retries %q", profileSource, raw)
+		}
+		opts.api.retries = retries
+	}
+
+	envAuth := strings.TrimSpace(os.Getenv(authURLEnv))
+	switch auth := fields[config.ProfileAuthURL]; {
+	case auth != "" && (profileFlag || envAuth == ""):
+		opts.api.authURL = auth
+	case envAuth != "":
+		opts.api.authURL = envAuth
+	default:
+		opts.api.authURL = opts.api.baseURL
+	}
+
+	if err := applyToken(opts, fields, profileSource); err != nil {
+		return err
+	}
+	if err := applyTransport(opts, fields); err != nil {
+		return err
+	}
+	slog.Debug("resolved API settings", "profile", name, "baseURL", opts.api.baseURL,
+		"timeout", opts.api.timeout, "retries", opts.api.retries, "token", opts.api.token != "",
+		"caCert", opts.api.caCert, "clientCert", opts.api.clientCert, "proxy", redactURL(opts.api.proxy))
+	return nil
+}
+
+// applyToken resolves the bearer token from, in order, BFAST_TOKEN, the
+// credential stored by bfast login, and the profile's token field. A stored
+// credential is only used against the API it was issued by.
+func applyToken(opts *options, fields map[string]string, profileSource string) error {
+	if token := strings.TrimSpace(os.Getenv(tokenEnv)); token != "" {
+		opts.api.token = token
+		opts.sources["token"] = "env"
+		return nil
+	}
+
+	cred, err := config.LoadCredential(credentialName(opts))
+	if err != nil {
+		return err
+	}
+	switch {
+	case cred != nil && sameBaseURL(cred.BaseURL, opts.api.baseURL):
+		path, _ := config.CredentialsPath()
+		opts.api.token = cred.Token
+		opts.sources["token"] = path
+		return nil
+	case cred != nil:
+		opts.warnings = append(opts.warnings, fmt.Sprintf("not sending the stored login token for %s to %s; run bfast login again for it",
+			canonicalBaseURL(cred.BaseURL), canonicalBaseURL(opts.api.baseURL)))
+		slog.Debug("skipped stored login token", "loggedIn", canonicalBaseURL(cred.BaseURL), "api", canonicalBaseURL(opts.api.baseURL))
+	}
+
+	if token := fields[config.ProfileToken]; token != "" {
+		opts.api.token = token
+		opts.sources["token"] = profileSource
+	}
+	return nil
+}
+
+// sameBaseURL reports whether two API base URLs name the same server, with
+// an empty URL meaning the default API.
+func sameBaseURL(a, b string) bool {
+	return canonicalBaseURL(a) == canonicalBaseURL(b)
+}
+
+func canonicalBaseURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		raw = api.DefaultBaseURL
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimRight(u.Path, "/")
+	return u.String()
+}
+
+// credentialName is the credentials entry for the selected profile.
+func credentialName(opts *options) string {
+	if opts.profile == "" {
+		return config.DefaultCredential
+	}
+	return opts.profile
+}
+
+// repoReadmePath resolves a readme path from the repo config against the git
+// root and rejects absolute paths and any path, symlinks included, that ends
+// up outside the root.
+func repoReadmePath(root, value string) (string, error) {
+	if filepath.IsAbs(value) || filepath.VolumeName(value) != "" {
+		return "", fmt.Errorf("%w: %s is absolute", errReadmeOutsideRepo, value)
+	}
+
+	path := filepath.Join(root, value)
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if errors.Is(err, os.ErrNotExist) {
+		// Missing files are reported when the README is read; check the
+		// lexical path.
+		realRoot, realPath = root, path
+	} else if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(realRoot, realPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", errReadmeOutsideRepo, value)
+	}
+	return path, nil
+}
+
+func envLayer() settingLayer {
+	values := map[string]string{}
+	for key, name := range settingEnv {
+		if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+			values[key] = value
+		}
+	}
+	return settingLayer{source: "env", values: values}
+}
+
+func flagProvided(opts *options, key string) bool {
+	switch key {
+	case config.KeyBlurb:
+		return opts.blurbProvided
+	case config.KeyHidden:
+		return opts.hiddenProvided
+	case config.KeyReadme:
+		return opts.readmeInput != ""
+	case config.KeyRepo:
+		return opts.repoInput != ""
+	case config.KeyPlacement:
+		return opts.placement != ""
+	case config.KeyRemote:
+		return opts.remote != ""
+	case config.KeyForkCheck:
+		return opts.forkCheck != ""
+	case config.KeyForkAPI:
+		return opts.forkAPIProvided
+	}
+	return false
+}
+
+func setOption(opts *options, key, value string) error {
+	switch key {
+	case config.KeyBlurb:
+		opts.blurb = value
+		opts.blurbProvided = true
+	case config.KeyHidden:
+		hidden, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		opts.hidden = hidden
+		opts.hiddenProvided = true
+	case config.KeyReadme:
+		opts.readmeInput = value
+	case config.KeyRepo:
+		opts.repoInput = value
+	case config.KeyPlacement:
+		opts.placement = value
+	case config.KeyRemote:
+		opts.remote = value
+	case config.KeyRemotes:
+		opts.remotes = splitList(value)
+	case config.KeyForkCheck:
+		opts.forkCheck = value
+	case config.KeyForkAPI:
+		forkAPI, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		opts.forkAPI = forkAPI
+		opts.forkAPIProvided = true
+	case config.KeyForges:
+		forges, err := normalize.ParseHosts(value)
+		if err != nil {
+			return err
+		}
+		opts.forges = forges
+	}
+	return nil
+}
+
+// splitList splits a comma-separated setting, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/arrno/bfast/internal/api"
+	"github.com/arrno/bfast/internal/readme"
+)
+
+type statusResult struct {
+	Repo        string `json:"repo"`
+	RepoURL     string `json:"repoUrl"`
+	Readme      string `json:"readme"`
+	Badged      bool   `json:"badged"`
+	Registered  bool   `json:"registered"`
+	Hidden      bool   `json:"hidden"`
+	Blurb       string `json:"blurb,omitempty"`
+	LookupError string `json:"lookupError,omitempty"`
+}
+
+// runStatus reports the detected repo, whether its README carries the badge,
+// and how the API has it registered. It never writes anything.
+func runStatus(ctx context.Context, opts *options, stdout, stderr io.Writer) error {
+	tgt, err := resolveTarget(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	res := &statusResult{
+		Repo:    tgt.slug.String(),
+		RepoURL: tgt.slug.RepoURL(),
+	}
+
+	file, err := loadReadme(tgt, opts.readmeInput)
+	switch {
+	case err == nil:
+		res.Readme = file.path
+		res.Badged = readme.HasBadge(file.content)
+	case errors.Is(err, readme.ErrNotFound):
+	default:
+		return err
+	}
+
+	project, err := newAPIClient(opts).Lookup(ctx, res.RepoURL)
+	switch {
+	case err == nil:
+		res.Registered = true
+		res.Hidden = project.Hidden
+		res.Blurb = project.Blurb
+	case errors.Is(err, api.ErrNotRegistered):
+	default:
+		res.LookupError = err.Error()
+	}
+
+	if opts.json {
+		emitJSON(res, stdout)
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "Repo:       %s (%s)\n", res.Repo, res.RepoURL)
+	switch {
+	case res.Readme == "":
+		fmt.Fprintln(stdout, "README:     not found")
+	case res.Badged:
+		fmt.Fprintf(stdout, "README:     %s (badged)\n", res.Readme)
+	default:
+		fmt.Fprintf(stdout, "README:     %s (not badged)\n", res.Readme)
+	}
+	switch {
+	case res.LookupError != "":
+		fmt.Fprintf(stdout, "Registered: unknown (%s)\n", res.LookupError)
+	case !res.Registered:
+		fmt.Fprintln(stdout, "Registered: no")
+	default:
+		fmt.Fprintf(stdout, "Registered: yes (hidden: %t)\n", res.Hidden)
+		fmt.Fprintf(stdout, "Blurb:      \"%s\"\n", res.Blurb)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/arrno/bfast/internal/har"
+)
+
+// startTrace sets up the --trace-http recording. It opens the file up front
+// so that a path that cannot be written fails the command before any request
+// is made, rather than after its output.
+func startTrace(opts *options) error {
+	f, err := os.OpenFile(opts.traceHTTP, os.O_WRONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("cannot write HTTP trace: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("cannot write HTTP trace: %w", err)
+	}
+	opts.transport = har.NewRecorder(nil)
+	return nil
+}
+
+// writeTrace saves the --trace-http recording once the command finishes,
+// whether it succeeded or not. Tokens resolved during the run are scrubbed
+// from the file as well as the Authorization headers.
+func writeTrace(opts *options) error {
+	opts.transport.AddSecret(opts.api.token, githubToken())
+	return opts.transport.WriteFile(opts.traceHTTP)
+}
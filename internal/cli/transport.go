@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/arrno/bfast/internal/api"
+	"github.com/arrno/bfast/internal/config"
+)
+
+const (
+	caBundleEnv   = "BFAST_CA_BUNDLE"
+	clientCertEnv = "BFAST_CLIENT_CERT"
+	clientKeyEnv  = "BFAST_CLIENT_KEY"
+	proxyEnv      = "BFAST_PROXY"
+
+	// proxyNone turns off proxying, including HTTP_PROXY and HTTPS_PROXY.
+	proxyNone = "none"
+)
+
+var errClientKeyOnly = errors.New("client key given without a client certificate")
+
+// applyTransport resolves the CA bundle, client certificate, and proxy from,
+// in order, flags, the environment, and the profile, and builds the API
+// transport from them. When none are set the transport stays nil, so the
+// default transport and its HTTP_PROXY handling apply.
+func applyTransport(opts *options, fields map[string]string) error {
+	opts.api.caCert = firstSetting(opts.caCert, caBundleEnv, fields[config.ProfileCACert])
+	opts.api.clientCert = firstSetting(opts.clientCert, clientCertEnv, fields[config.ProfileClientCert])
+	opts.api.clientKey = firstSetting(opts.clientKey, clientKeyEnv, fields[config.ProfileClientKey])
+	opts.api.proxy = firstSetting(opts.proxy, proxyEnv, fields[config.ProfileProxy])
+
+	if opts.api.caCert == "" && opts.api.clientCert == "" && opts.api.clientKey == "" && opts.api.proxy == "" {
+		return nil
+	}
+
+	transport, err := newTransport(opts.api)
+	if err != nil {
+		return err
+	}
+	opts.api.transport = transport
+	return nil
+}
+
+// firstSetting returns the flag value, then the environment variable, then
+// the profile value, whichever is set first.
+func firstSetting(flagValue, env, profileValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if value := strings.TrimSpace(os.Getenv(env)); value != "" {
+		return value
+	}
+	return profileValue
+}
+
+// newTransport clones http.DefaultTransport and applies the CA bundle,
+// client certificate, and proxy from settings. The CA bundle is added to the
+// system roots rather than replacing them.
+func newTransport(settings apiSettings) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if settings.caCert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(settings.caCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %s contains no PEM certificates", settings.caCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	switch {
+	case settings.clientCert != "":
+		key := settings.clientKey
+		if key == "" {
+			key = settings.clientCert
+		}
+		pair, err := tls.LoadX509KeyPair(settings.clientCert, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	case settings.clientKey != "":
+		return nil, errClientKeyOnly
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	switch settings.proxy {
+	case "":
+	case proxyNone:
+		transport.Proxy = nil
+	default:
+		proxyURL, err := url.Parse(settings.proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", settings.proxy)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("proxy URL %q must use http, https, or socks5", settings.proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return transport, nil
+}
+
+// roundTripper returns the transport for API requests: the configured
+// transport, wrapped by the --trace-http recorder when there is one. It
+// returns nil for the default transport; a nil *http.Transport must not leak
+// into an http.Client as a non-nil interface.
+func (o *options) roundTripper() http.RoundTripper {
+	var base http.RoundTripper
+	if o.api.transport != nil {
+		base = o.api.transport
+	}
+	if o.transport == nil {
+		return base
+	}
+	return o.transport.Wrap(base)
+}
+
+// githubHTTPClient uses the API transport's CA bundle and proxy for GitHub,
+// but not its client certificate, which is meant for the blazingly-fast API.
+// It returns nil, the client default, when nothing is configured.
+func githubHTTPClient(opts *options) *http.Client {
+	var base http.RoundTripper
+	if opts.api.transport != nil {
+		transport := opts.api.transport.Clone()
+		transport.TLSClientConfig.Certificates = nil
+		base = transport
+	}
+	if opts.transport != nil {
+		base = opts.transport.Wrap(base)
+	}
+	if base == nil {
+		return nil
+	}
+
+	timeout := opts.api.timeout
+	if timeout <= 0 {
+		timeout = api.DefaultTimeout
+	}
+	return &http.Client{Timeout: timeout, Transport: base}
+}
+
+// networkSummary describes the transport settings for bfast doctor.
+func networkSummary(settings apiSettings) string {
+	var parts []string
+	if settings.caCert != "" {
+		parts = append(parts, "CA bundle "+settings.caCert)
+	}
+	if settings.clientCert != "" {
+		parts = append(parts, "client certificate "+settings.clientCert)
+	}
+	switch settings.proxy {
+	case "":
+		parts = append(parts, "proxy from environment")
+	case proxyNone:
+		parts = append(parts, "no proxy")
+	default:
+		parts = append(parts, "proxy "+redactURL(settings.proxy))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// redactURL hides the password in a proxy URL.
+func redactURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return parsed.Redacted()
+}
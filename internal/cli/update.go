@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/arrno/bfast/internal/api"
+	"github.com/arrno/bfast/internal/blurb"
+	"github.com/arrno/bfast/internal/config"
+)
+
+var errNothingToUpdate = errors.New("nothing to update; pass -m/--blurb or --hidden")
+
+type updateResult struct {
+	Repo     string `json:"repo"`
+	RepoURL  string `json:"repoUrl"`
+	Blurb    string `json:"blurb,omitempty"`
+	Hidden   *bool  `json:"hidden,omitempty"`
+	Updated  bool   `json:"updated"`
+	DryRun   bool   `json:"dryRun"`
+	Attempts int    `json:"attempts,omitempty"`
+}
+
+// runUpdate changes the blurb and/or hidden flag of an existing registration.
+// Only fields passed as flags are sent: a blurb or hidden setting from the
+// environment or a config file describes new registrations, not a change the
+// user asked for.
+func runUpdate(ctx context.Context, opts *options, stdout, stderr io.Writer) error {
+	tgt, err := resolveTarget(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	blurbFlag := opts.sources[config.KeyBlurb] == sourceFlag
+	hiddenFlag := opts.sources[config.KeyHidden] == sourceFlag
+	if !blurbFlag && !hiddenFlag {
+		return errNothingToUpdate
+	}
+
+	res := &updateResult{
+		Repo:    tgt.slug.String(),
+		RepoURL: tgt.slug.RepoURL(),
+		DryRun:  opts.dryRun,
+	}
+	payload := api.ProjectUpdate{RepoURL: res.RepoURL}
+
+	if blurbFlag {
+		text, err := blurb.Normalize(opts.blurb)
+		if err != nil {
+			return err
+		}
+		payload.Blurb = &text
+		res.Blurb = text
+	}
+	if hiddenFlag {
+		hidden := opts.hidden
+		payload.Hidden = &hidden
+		res.Hidden = &hidden
+	}
+
+	if !opts.dryRun {
+		client := newAPIClient(opts)
+		project, err := client.Update(ctx, payload)
+		res.Attempts = client.Attempts()
+		if err != nil {
+			if errors.Is(err, api.ErrNotRegistered) {
+				return &hintError{msg: fmt.Sprintf("%s is not registered yet. Use: bfast register", res.Repo), err: err}
+			}
+			return err
+		}
+		if project != nil {
+			res.Blurb = project.Blurb
+			res.Hidden = &project.Hidden
+		}
+		res.Updated = true
+	}
+
+	if opts.json {
+		emitJSON(res, stdout)
+		return nil
+	}
+
+	if res.DryRun {
+		fmt.Fprintf(stdout, "Dry run: would update %s\n", res.Repo)
+	} else {
+		fmt.Fprintf(stdout, "Updated %s\n", res.Repo)
+	}
+	if res.Blurb != "" {
+		fmt.Fprintf(stdout, "Blurb: \"%s\"\n", res.Blurb)
+	}
+	if res.Hidden != nil {
+		fmt.Fprintf(stdout, "Hidden: %t\n", *res.Hidden)
+	}
+	return nil
+}
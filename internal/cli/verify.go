@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/arrno/bfast/internal/api"
+	"github.com/arrno/bfast/internal/git"
+)
+
+const (
+	defaultVerifyMessage = "Add blazingly fast verification token"
+	// verifyBranch is the remote branch the challenge is published on, away
+	// from any branch the user works on or protects.
+	verifyBranch = "bfast/verify"
+)
+
+var (
+	errVerifyOutsideRepo  = errors.New("--verify needs a git repository to publish the challenge in")
+	errVerificationFailed = errors.New("ownership verification failed")
+)
+
+// checkVerify runs before anything is written or registered so that a
+// failed precondition costs nothing.
+func checkVerify(ctx context.Context, opts *options, tgt *target) error {
+	if !opts.verify {
+		return nil
+	}
+	if tgt.root == "" {
+		return errVerifyOutsideRepo
+	}
+	push, err := pushRemote(ctx, opts, tgt)
+	if err != nil {
+		return err
+	}
+	if !sameRepo(push.Slug, tgt.slug) {
+		return fmt.Errorf("--verify would publish the challenge on remote %s, which is %s, not %s; pick the remote of %s with --remote", push.Remote, push.Slug, tgt.slug, tgt.slug)
+	}
+	return nil
+}
+
+// verifyOwnership proves control of the repository before it is registered:
+// it asks the API for a challenge, pushes a commit holding only the token at
+// the path the API names to a dedicated branch of the target's remote, and
+// asks the API to check that branch. The checkout and local branches are left
+// alone, and the remote branch is deleted once verified. It returns the verified challenge
+// token to send with the submission, or "" when --verify was not requested.
+func verifyOwnership(ctx context.Context, client *api.Client, opts *options, tgt *target, res *result) (string, error) {
+	if !opts.verify {
+		return "", nil
+	}
+
+	challenge, err := client.RequestChallenge(ctx, res.RepoURL)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := challengePath(challenge.Path)
+	if err != nil {
+		return "", err
+	}
+	res.VerificationFile = challenge.Path
+
+	push, err := pushRemote(ctx, opts, tgt)
+	if err != nil {
+		return "", err
+	}
+	remote := push.Remote
+	if _, err := git.PushFile(ctx, tgt.root, remote, verifyBranch, rel, challenge.Token+"\n", defaultVerifyMessage); err != nil {
+		return "", err
+	}
+
+	verification, err := client.Verify(ctx, res.RepoURL, challenge.Token, verifyBranch)
+	if err == nil && !verification.Verified {
+		reason := verification.Message
+		if reason == "" {
+			reason = fmt.Sprintf("the API could not find the challenge in %s on %s", challenge.Path, verifyBranch)
+		}
+		err = fmt.Errorf("%w: %s", errVerificationFailed, reason)
+	}
+	if err != nil {
+		msg := fmt.Sprintf("%s. The challenge is still on branch %s of %s; remove it with: git push %s --delete %s", err, verifyBranch, remote, remote, verifyBranch)
+		return "", &hintError{msg: msg, err: err}
+	}
+
+	if err := git.DeleteRemoteBranch(ctx, tgt.root, remote, verifyBranch); err != nil {
+		res.warn("verified, but could not remove branch %s: %s", verifyBranch, err)
+	}
+	res.Verified = true
+	return challenge.Token, nil
+}
+
+// challengePath cleans the API-provided challenge path into a slash-separated
+// path inside the repository, refusing paths that would escape it.
+func challengePath(rel string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(rel, "\\", "/"))
+	if path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("invalid challenge path %q from the API", rel)
+	}
+	return clean, nil
+}
+
+func printVerification(stdout io.Writer, res *result) {
+	if !res.Verified {
+		return
+	}
+	fmt.Fprintf(stdout, "Ownership verified via %s\n", res.VerificationFile)
+}
@@ -0,0 +1,453 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Setting keys understood in config files.
+const (
+	KeyBlurb     = "blurb"
+	KeyHidden    = "hidden"
+	KeyReadme    = "readme"
+	KeyRepo      = "repo"
+	KeyPlacement = "placement"
+	KeyRemote    = "remote"
+	KeyRemotes   = "remotes"
+	KeyForkCheck = "fork_check"
+	KeyForkAPI   = "fork_api"
+	KeyForges    = "forges"
+)
+
+// KeyProfile names the default API profile. It is only valid in the user
+// config.
+const KeyProfile = "profile"
+
+// Keys lists every setting key in the order they are resolved.
+var Keys = []string{KeyBlurb, KeyHidden, KeyReadme, KeyRepo, KeyPlacement, KeyRemote, KeyRemotes, KeyForkCheck, KeyForkAPI, KeyForges}
+
+// Profile field keys, set under a [profile.<name>] section of the user config.
+const (
+	ProfileBaseURL    = "base_url"
+	ProfileTimeout    = "timeout"
+	ProfileToken      = "token"
+	ProfileRetries    = "retries"
+	ProfileAuthURL    = "auth_url"
+	ProfileCACert     = "ca_cert"
+	ProfileClientCert = "client_cert"
+	ProfileClientKey  = "client_key"
+	ProfileProxy      = "proxy"
+)
+
+// ProfileKeys lists every field a profile may set.
+var ProfileKeys = []string{ProfileBaseURL, ProfileTimeout, ProfileToken, ProfileRetries, ProfileAuthURL, ProfileCACert, ProfileClientCert, ProfileClientKey, ProfileProxy}
+
+const profilePrefix = "profile."
+
+// RepoFileNames are the per-repository config files looked up at the git
+// root. The first one that exists wins.
+var RepoFileNames = []string{".bfast.toml", ".bfast.yaml", ".bfast.yml", ".bfast.json"}
+
+// File is a parsed config file. Values holds raw strings keyed by setting;
+// callers convert them to the type each setting needs. Profiles is only
+// populated for the user config.
+type File struct {
+	Path     string
+	Values   map[string]string
+	Profiles map[string]map[string]string
+}
+
+// LoadRepo reads the first per-repository config file found in root. It
+// returns nil without error when none exists.
+func LoadRepo(root string) (*File, error) {
+	for _, name := range RepoFileNames {
+		path := filepath.Join(root, name)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+		return Parse(path, data)
+	}
+	return nil, nil
+}
+
+// UserPath returns the location of the user config file:
+// $XDG_CONFIG_HOME/bfast/config, falling back to the OS user config dir.
+func UserPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		var err error
+		dir, err = os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, "bfast", "config"), nil
+}
+
+// LoadUser reads the user config file. It returns an empty File when the
+// file does not exist yet.
+func LoadUser() (*File, error) {
+	path, err := UserPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &File{Path: path, Values: map[string]string{}, Profiles: map[string]map[string]string{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return ParseUser(path, data)
+}
+
+// Parse decodes config data using the format implied by the file extension:
+// JSON for .json, "key: value" for .yaml/.yml, and "key = value" otherwise.
+// Only flat string and boolean values are supported.
+func Parse(path string, data []byte) (*File, error) {
+	file := &File{Path: path}
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		file.Values, err = parseJSON(data)
+	case ".yaml", ".yml":
+		err = parseFlat(file, data, ":", false)
+	default:
+		err = parseFlat(file, data, "=", false)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+// ParseUser decodes the user config, which uses the "key = value" format and
+// may additionally set the default profile and [profile.<name>] sections.
+func ParseUser(path string, data []byte) (*File, error) {
+	file := &File{Path: path}
+	if err := parseFlat(file, data, "=", true); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+// Get returns the value for a dotted key: a setting such as "blurb", the
+// default "profile", or a profile field such as "profile.staging.base_url".
+func (f *File) Get(key string) (string, bool) {
+	profile, field, isProfile := splitProfileKey(key)
+	if isProfile {
+		value, ok := f.Profiles[profile][field]
+		return value, ok
+	}
+	value, ok := f.Values[key]
+	return value, ok
+}
+
+// Entries returns every value in the file as dotted keys, sorted.
+func (f *File) Entries() []Entry {
+	var entries []Entry
+	for key, value := range f.Values {
+		entries = append(entries, Entry{Key: key, Value: value})
+	}
+	for name, fields := range f.Profiles {
+		for field, value := range fields {
+			entries = append(entries, Entry{Key: profilePrefix + name + "." + field, Value: value})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
+
+// Entry is a single dotted key and its value.
+type Entry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ValidateUserKey reports whether key may be set in the user config.
+func ValidateUserKey(key string) error {
+	profile, field, isProfile := splitProfileKey(key)
+	switch {
+	case isProfile:
+		if profile == "" || !knownProfileKey(field) {
+			return fmt.Errorf("unknown key %q (profile fields are %s)", key, strings.Join(ProfileKeys, ", "))
+		}
+	case key != KeyProfile && !knownKey(key):
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return nil
+}
+
+// SetUserValue writes key = value into the user config file at path,
+// replacing an existing assignment in place or appending one. Comments and
+// unrelated lines are preserved. The file is created with owner-only
+// permissions because profiles may hold tokens.
+func SetUserValue(path, key, value string) error {
+	if err := ValidateUserKey(key); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	if _, err := ParseUser(path, data); err != nil {
+		return err
+	}
+
+	section := ""
+	name := key
+	if profile, field, ok := splitProfileKey(key); ok {
+		section = profilePrefix + profile
+		name = field
+	}
+
+	lines := setLine(splitLines(string(data)), section, name, name+" = "+strconv.Quote(value))
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	// Profiles may hold tokens, and WriteFile keeps the mode of an existing
+	// file, so tighten it explicitly.
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+func splitLines(content string) []string {
+	content = strings.TrimRight(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if content == "" {
+		return nil
+	}
+	return strings.Split(content, "\n")
+}
+
+// setLine replaces the assignment to name inside section, or inserts one
+// after the section's last assignment, adding the section header if needed.
+func setLine(lines []string, section, name, assignment string) []string {
+	current := ""
+	insertAt := -1
+	if section == "" {
+		insertAt = 0
+	}
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if header, ok := sectionHeader(trimmed); ok {
+			current = header
+			if current == section {
+				insertAt = i + 1
+			}
+			continue
+		}
+		if current != section || trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		key, _, _ := strings.Cut(trimmed, "=")
+		if strings.TrimSpace(key) == name {
+			lines[i] = assignment
+			return lines
+		}
+		insertAt = i + 1
+	}
+
+	if insertAt >= 0 {
+		out := make([]string, 0, len(lines)+2)
+		out = append(out, lines[:insertAt]...)
+		out = append(out, assignment)
+		if insertAt < len(lines) {
+			if _, ok := sectionHeader(strings.TrimSpace(lines[insertAt])); ok {
+				out = append(out, "")
+			}
+		}
+		return append(out, lines[insertAt:]...)
+	}
+
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+	return append(lines, "["+section+"]", assignment)
+}
+
+func sectionHeader(line string) (string, bool) {
+	if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+		return "", false
+	}
+	return strings.TrimSpace(line[1 : len(line)-1]), true
+}
+
+func splitProfileKey(key string) (profile, field string, ok bool) {
+	if !strings.HasPrefix(key, profilePrefix) {
+		return "", "", false
+	}
+	rest := strings.TrimPrefix(key, profilePrefix)
+	idx := strings.LastIndex(rest, ".")
+	if idx < 0 {
+		return rest, "", true
+	}
+	return rest[:idx], rest[idx+1:], true
+}
+
+func parseJSON(data []byte) (map[string]string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for key, msg := range raw {
+		if !knownKey(key) {
+			return nil, fmt.Errorf("unknown key %q", key)
+		}
+
+		var str string
+		if err := json.Unmarshal(msg, &str); err == nil {
+			values[key] = str
+			continue
+		}
+		var b bool
+		if err := json.Unmarshal(msg, &b); err == nil {
+			values[key] = strconv.FormatBool(b)
+			continue
+		}
+		return nil, fmt.Errorf("key %q must be a string or boolean", key)
+	}
+	return values, nil
+}
+
+func parseFlat(file *File, data []byte, sep string, user bool) error {
+	file.Values = map[string]string{}
+	if user {
+		file.Profiles = map[string]map[string]string{}
+	}
+
+	profile := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+
+		if header, ok := sectionHeader(line); ok {
+			name := strings.TrimPrefix(header, profilePrefix)
+			if !user || !strings.HasPrefix(header, profilePrefix) || name == "" {
+				return fmt.Errorf("line %d: unsupported section %q", lineNo, header)
+			}
+			profile = name
+			if file.Profiles[profile] == nil {
+				file.Profiles[profile] = map[string]string{}
+			}
+			continue
+		}
+
+		key, rawValue, ok := strings.Cut(line, sep)
+		if !ok {
+			return fmt.Errorf("line %d: expected key %s value", lineNo, sep)
+		}
+		key = strings.TrimSpace(key)
+
+		value, err := parseValue(strings.TrimSpace(rawValue))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		switch {
+		case profile != "":
+			if !knownProfileKey(key) {
+				return fmt.Errorf("line %d: unknown profile key %q", lineNo, key)
+			}
+			file.Profiles[profile][key] = value
+		case knownKey(key), user && key == KeyProfile:
+			file.Values[key] = value
+		default:
+			return fmt.Errorf("line %d: unknown key %q", lineNo, key)
+		}
+	}
+	return scanner.Err()
+}
+
+// parseValue accepts a double-quoted string, a single-quoted literal, or a
+// bare word, each optionally followed by a # comment.
+func parseValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		end := closingQuote(raw)
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		value, err := strconv.Unquote(raw[:end+1])
+		if err != nil {
+			return "", err
+		}
+		return value, trailingComment(raw[end+1:])
+	case strings.HasPrefix(raw, "'"):
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		return raw[1 : end+1], trailingComment(raw[end+2:])
+	default:
+		if idx := strings.Index(raw, " #"); idx >= 0 {
+			raw = raw[:idx]
+		}
+		return strings.TrimSpace(raw), nil
+	}
+}
+
+func closingQuote(raw string) int {
+	for i := 1; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func trailingComment(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest == "" || strings.HasPrefix(rest, "#") {
+		return nil
+	}
+	return fmt.Errorf("unexpected text after value: %q", rest)
+}
+
+func knownProfileKey(key string) bool {
+	for _, k := range ProfileKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func knownKey(key string) bool {
+	for _, k := range Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFormats(t *testing.T) {
+	want := map[string]string{
+		KeyBlurb:  "Fast # really",
+		KeyHidden: "true",
+		KeyReadme: "docs/README.md",
+	}
+
+	cases := map[string]string{
+		".bfast.toml": "# team defaults\nblurb = \"Fast # really\"\nhidden = true\nreadme = 'docs/README.md' # relative to root\n",
+		".bfast.yaml": "---\nblurb: \"Fast # really\"\nhidden: true\nreadme: docs/README.md\n",
+		".bfast.json": `{"blurb": "Fast # really", "hidden": true, "readme": "docs/README.md"}`,
+	}
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			file, err := Parse(name, []byte(data))
+			if err != nil {
+				t.Fatalf("Parse returned error: %v", err)
+			}
+			if len(file.Values) != len(want) {
+				t.Fatalf("Parse values = %v, want %v", file.Values, want)
+			}
+			for key, value := range want {
+				if file.Values[key] != value {
+					t.Fatalf("%s = %q, want %q", key, file.Values[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRejectsUnknownKeys(t *testing.T) {
+	if _, err := Parse(".bfast.toml", []byte("speed = 11\n")); err == nil {
+		t.Fatal("expected error for unknown key")
+	}
+	if _, err := Parse(".bfast.json", []byte(`{"speed": 11}`)); err == nil {
+		t.Fatal("expected error for unknown key")
+	}
+}
+
+func TestLoadRepoMissing(t *testing.T) {
+	file, err := LoadRepo(t.TempDir())
+	if err != nil || file != nil {
+		t.Fatalf("LoadRepo = %v, %v; want nil, nil", file, err)
+	}
+}
+
+func TestLoadRepoPrefersFirstName(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, ".bfast.json"), []byte(`{"repo": "a/json"}`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".bfast.toml"), []byte(`repo = "a/toml"`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	file, err := LoadRepo(root)
+	if err != nil {
+		t.Fatalf("LoadRepo returned error: %v", err)
+	}
+	if file.Values[KeyRepo] != "a/toml" {
+		t.Fatalf("repo = %q, want a/toml", file.Values[KeyRepo])
+	}
+}
+
+func TestParseUserProfiles(t *testing.T) {
+	data := "profile = \"staging\"\nblurb = \"Mine\"\n\n[profile.staging]\nbase_url = \"https://staging.example\"\ntimeout = \"30s\"\n"
+	file, err := ParseUser("config", []byte(data))
+	if err != nil {
+		t.Fatalf("ParseUser returned error: %v", err)
+	}
+
+	if got, _ := file.Get("profile"); got != "staging" {
+		t.Fatalf("profile = %q", got)
+	}
+	if got, _ := file.Get("profile.staging.base_url"); got != "https://staging.example" {
+		t.Fatalf("base_url = %q", got)
+	}
+	if _, err := Parse(".bfast.toml", []byte(data)); err == nil {
+		t.Fatal("repo config should reject profiles")
+	}
+}
+
+func TestSetUserValuePreservesComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bfast", "config")
+	if err := SetUserValue(path, "profile.staging.base_url", "https://old.example"); err != nil {
+		t.Fatalf("SetUserValue returned error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if err := os.WriteFile(path, append([]byte("# staging API\n"), data...), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	// A config created with looser permissions is tightened on the next write.
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+
+	steps := []struct{ key, value string }{
+		{"profile.staging.base_url", "https://staging.example"},
+		{"blurb", "Mine"},
+		{"profile.staging.token", "secret"},
+		{"profile.staging.base_url", "https://staging2.example"},
+	}
+	for _, step := range steps {
+		if err := SetUserValue(path, step.key, step.value); err != nil {
+			t.Fatalf("SetUserValue(%s) returned error: %v", step.key, err)
+		}
+	}
+
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	want := "blurb = \"Mine\"\n# staging API\n[profile.staging]\nbase_url = \"https://staging2.example\"\ntoken = \"secret\"\n"
+	if string(data) != want {
+		t.Fatalf("config = %q, want %q", string(data), want)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("config mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	if err := SetUserValue(path, "profile.staging.speed", "11"); err == nil {
+		t.Fatal("expected error for unknown profile key")
+	}
+}
+
+func TestCredentialsRoundTrip(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	if cred, err := LoadCredential(DefaultCredential); err != nil || cred != nil {
+		t.Fatalf("expected no credential, got %+v, %v", cred, err)
+	}
+
+	if err := SaveCredential("staging", Credential{Token: "tok-1"}); err != nil {
+		t.Fatalf("SaveCredential: %v", err)
+	}
+	if err := SaveCredential(DefaultCredential, Credential{Token: "tok-2"}); err != nil {
+		t.Fatalf("SaveCredential: %v", err)
+	}
+
+	path, _ := CredentialsPath()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat credentials: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("credentials mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	cred, err := LoadCredential("staging")
+	if err != nil || cred == nil || cred.Token != "tok-1" {
+		t.Fatalf("unexpected credential: %+v, %v", cred, err)
+	}
+
+	for _, name := range []string{"staging", DefaultCredential} {
+		if ok, err := DeleteCredential(name); err != nil || !ok {
+			t.Fatalf("DeleteCredential(%s) = %t, %v", name, ok, err)
+		}
+	}
+	if ok, _ := DeleteCredential("staging"); ok {
+		t.Fatal("deleting a missing credential should report false")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the empty credentials file to be removed, got %v", err)
+	}
+}
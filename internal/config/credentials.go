@@ -0,0 +1,114 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultCredential is the credentials entry used when no profile is
+// selected.
+const DefaultCredential = "default"
+
+// Credential is a stored API token.
+type Credential struct {
+	Token     string    `json:"token"`
+	BaseURL   string    `json:"baseUrl,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CredentialsPath returns the credentials file, next to the user config.
+func CredentialsPath() (string, error) {
+	path, err := UserPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "credentials.json"), nil
+}
+
+// LoadCredential returns the stored credential for name, or nil when there
+// is none.
+func LoadCredential(name string) (*Credential, error) {
+	creds, _, err := loadCredentials()
+	if err != nil {
+		return nil, err
+	}
+	cred, ok := creds[name]
+	if !ok {
+		return nil, nil
+	}
+	return &cred, nil
+}
+
+// SaveCredential stores cred under name. The file is written with
+// owner-only permissions.
+func SaveCredential(name string, cred Credential) error {
+	creds, path, err := loadCredentials()
+	if err != nil {
+		return err
+	}
+	creds[name] = cred
+	return writeCredentials(path, creds)
+}
+
+// DeleteCredential removes the credential stored under name and reports
+// whether there was one. The file is removed once it is empty.
+func DeleteCredential(name string) (bool, error) {
+	creds, path, err := loadCredentials()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := creds[name]; !ok {
+		return false, nil
+	}
+	delete(creds, name)
+	if len(creds) == 0 {
+		if err := os.Remove(path); err != nil {
+			return false, fmt.Errorf("failed to remove credentials: %w", err)
+		}
+		return true, nil
+	}
+	return true, writeCredentials(path, creds)
+}
+
+func loadCredentials() (map[string]Credential, string, error) {
+	path, err := CredentialsPath()
+	if err != nil {
+		return nil, "", err
+	}
+
+	creds := map[string]Credential{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return creds, path, nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read credentials: %w", err)
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	return creds, path, nil
+}
+
+func writeCredentials(path string, creds map[string]Credential) error {
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file, so tighten it explicitly
+	// in case the file was created or copied with looser permissions.
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around each change.
+const context = 3
+
+const (
+	colorReset = "\x1b[0m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+	colorBold  = "\x1b[1m"
+)
+
+type op struct {
+	kind byte // ' ', '-', or '+'
+	text string
+}
+
+// Unified returns a unified diff of a and b labelled with the given file
+// names. It returns an empty string when the contents are equal.
+func Unified(fromLabel, toLabel, a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	ops := editScript(splitLines(a), splitLines(b))
+
+	// aLine and bLine hold the 0-based line numbers reached before each op.
+	aLine := make([]int, len(ops)+1)
+	bLine := make([]int, len(ops)+1)
+	for i, o := range ops {
+		aLine[i+1], bLine[i+1] = aLine[i], bLine[i]
+		if o.kind != '+' {
+			aLine[i+1]++
+		}
+		if o.kind != '-' {
+			bLine[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromLabel, toLabel)
+
+	for i := 0; i < len(ops); {
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		start := max(0, i-context)
+		end := i
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next < len(ops) && next-end <= 2*context {
+				end = next
+				continue
+			}
+			end = min(len(ops), end+context)
+			break
+		}
+
+		writeHunk(&out, ops[start:end], aLine[start], aLine[end], bLine[start], bLine[end])
+		i = end
+	}
+
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, ops []op, aStart, aEnd, bStart, bEnd int) {
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(aStart, aEnd-aStart), hunkRange(bStart, bEnd-bStart))
+	for _, o := range ops {
+		out.WriteByte(o.kind)
+		out.WriteString(o.text)
+		if !strings.HasSuffix(o.text, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats a range the way GNU diff does: the count is omitted when
+// it is one, and an empty range points at the line before it.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+// splitLines splits s into lines that keep their line endings, so a missing
+// final newline shows up as a change.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// editScript computes a shortest edit script. The common prefix and suffix
+// are matched directly, so that the longest common subsequence table only
+// spans the changed middle, which a badge edit keeps to a few lines.
+func editScript(a, b []string) []op {
+	var ops []op
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		ops = append(ops, op{' ', a[pre]})
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	ops = append(ops, lcsScript(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for _, line := range a[len(a)-suf:] {
+		ops = append(ops, op{' ', line})
+	}
+	return ops
+}
+
+// lcsScript computes a shortest edit script from a longest common
+// subsequence table.
+func lcsScript(a, b []string) []op {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []op
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, op{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, op{'+', b[j]})
+	}
+	return ops
+}
+
+// Colorize wraps the lines of a unified diff in ANSI colors.
+func Colorize(d string) string {
+	if d == "" {
+		return ""
+	}
+
+	lines := strings.SplitAfter(d, "\n")
+	var out strings.Builder
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		body := strings.TrimSuffix(line, "\n")
+		color := ""
+		switch {
+		case strings.HasPrefix(body, "---"), strings.HasPrefix(body, "+++"):
+			color = colorBold
+		case strings.HasPrefix(body, "@@"):
+			color = colorCyan
+		case strings.HasPrefix(body, "-"):
+			color = colorRed
+		case strings.HasPrefix(body, "+"):
+			color = colorGreen
+		}
+		if color == "" {
+			out.WriteString(line)
+			continue
+		}
+		out.WriteString(color + body + colorReset + line[len(body):])
+	}
+	return out.String()
+}
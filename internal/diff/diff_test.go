@@ -0,0 +1,77 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedEqual(t *testing.T) {
+	if got := Unified("a", "b", "same\n", "same\n"); got != "" {
+		t.Fatalf("Unified of equal input = %q, want empty", got)
+	}
+}
+
+func TestUnifiedInsertion(t *testing.T) {
+	before := "# Project\n\nSome text\n"
+	after := "# Project\n[badge]\n\nSome text\n"
+
+	want := "--- a/README.md\n+++ b/README.md\n" +
+		"@@ -1,3 +1,4 @@\n" +
+		" # Project\n" +
+		"+[badge]\n" +
+		" \n" +
+		" Some text\n"
+
+	if got := Unified("a/README.md", "b/README.md", before, after); got != want {
+		t.Fatalf("Unified =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnifiedSeparateHunks(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 20; i++ {
+		line := strings.Repeat("x", i+1)
+		a = append(a, line)
+		b = append(b, line)
+	}
+	b[1] = "changed"
+	b[18] = "changed too"
+
+	got := Unified("a", "b", strings.Join(a, "\n")+"\n", strings.Join(b, "\n")+"\n")
+	if strings.Count(got, "@@ ") != 2 {
+		t.Fatalf("expected two hunks:\n%s", got)
+	}
+	if !strings.Contains(got, "@@ -1,5 +1,5 @@") || !strings.Contains(got, "@@ -16,5 +16,5 @@") {
+		t.Fatalf("unexpected hunk headers:\n%s", got)
+	}
+}
+
+func TestUnifiedLargeInputSmallChange(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 10000; i++ {
+		b.WriteString("line ")
+		b.WriteString(strings.Repeat("x", i%7))
+		b.WriteString("\n")
+	}
+	before := "# Project\n\n" + b.String()
+	after := "# Project\n[badge]\n\n" + b.String()
+
+	got := Unified("a", "b", before, after)
+	if !strings.Contains(got, "@@ -1,4 +1,5 @@\n # Project\n+[badge]\n") || strings.Count(got, "@@ ") != 1 {
+		t.Fatalf("unexpected diff:\n%s", got)
+	}
+}
+
+func TestUnifiedMissingFinalNewline(t *testing.T) {
+	got := Unified("a", "b", "# Project", "# Project\n")
+	if !strings.Contains(got, "-# Project\n\\ No newline at end of file\n+# Project\n") {
+		t.Fatalf("missing newline marker not reported:\n%s", got)
+	}
+}
+
+func TestColorize(t *testing.T) {
+	got := Colorize("--- a\n+++ b\n@@ -1 +1 @@\n-old\n+new\n")
+	if !strings.Contains(got, colorRed+"-old"+colorReset+"\n") || !strings.Contains(got, colorGreen+"+new"+colorReset+"\n") {
+		t.Fatalf("unexpected colors: %q", got)
+	}
+}
@@ -0,0 +1,537 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxIncludeDepth matches git's limit on nested include and includeIf
+// sections.
+const maxIncludeDepth = 10
+
+// config is a parsed git configuration. Entries are kept in the order git
+// reads them (system, global, local, worktree, with includes expanded in
+// place), so the last value of a key wins.
+type config struct {
+	entries []configEntry
+}
+
+type configEntry struct {
+	section    string // lower case
+	subsection string // case-sensitive, empty when absent
+	key        string // lower case
+	value      string
+}
+
+// get returns the last value of section.subsection.key.
+func (c *config) get(section, subsection, key string) (string, bool) {
+	values := c.getAll(section, subsection, key)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// getAll returns every value of section.subsection.key in order.
+func (c *config) getAll(section, subsection, key string) []string {
+	var values []string
+	for _, e := range c.entries {
+		if e.section == section && e.subsection == subsection && e.key == key {
+			values = append(values, e.value)
+		}
+	}
+	return values
+}
+
+// subsections returns the subsections of section in the order they first
+// appear.
+func (c *config) subsections(section string) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, e := range c.entries {
+		if e.section == section && e.subsection != "" && !seen[e.subsection] {
+			seen[e.subsection] = true
+			names = append(names, e.subsection)
+		}
+	}
+	return names
+}
+
+// repoDirs resolves the git directory of the work tree at root, following a
+// .git file ("gitdir: <path>") as used by worktrees and submodules, and the
+// common directory named by its commondir file.
+func repoDirs(root string) (gitDir, commonDir string, err error) {
+	gitDir = filepath.Join(root, ".git")
+	info, err := os.Stat(gitDir)
+	if err != nil {
+		return "", "", err
+	}
+
+	if !info.IsDir() {
+		data, err := os.ReadFile(gitDir)
+		if err != nil {
+			return "", "", err
+		}
+		target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+		if !ok {
+			return "", "", fmt.Errorf("%s: not a gitdir file", gitDir)
+		}
+		gitDir = resolvePath(root, strings.TrimSpace(target))
+	}
+
+	commonDir = gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = resolvePath(gitDir, strings.TrimSpace(string(data)))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", "", err
+	}
+	return gitDir, commonDir, nil
+}
+
+func resolvePath(base, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(base, path)
+}
+
+// loadConfig reads the configuration git would use for the repository at
+// root: the system and global files, then the repository's own config and,
+// when enabled, its worktree config. Missing system and global files are
+// skipped; a missing repository config is an error.
+func loadConfig(root string) (*config, error) {
+	gitDir, commonDir, err := repoDirs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &configLoader{gitDir: gitDir, cfg: &config{}}
+	for _, path := range userConfigPaths() {
+		if err := l.load(path, 0, true); err != nil {
+			return nil, err
+		}
+	}
+	if err := l.load(filepath.Join(commonDir, "config"), 0, false); err != nil {
+		return nil, err
+	}
+	if enabled, _ := l.cfg.get("extensions", "", "worktreeconfig"); isTrue(enabled) {
+		if err := l.load(filepath.Join(gitDir, "config.worktree"), 0, true); err != nil {
+			return nil, err
+		}
+	}
+	return l.cfg, nil
+}
+
+// userConfigPaths lists the system and global config files in the order git
+// reads them, honoring GIT_CONFIG_SYSTEM, GIT_CONFIG_NOSYSTEM, and
+// GIT_CONFIG_GLOBAL.
+func userConfigPaths() []string {
+	var paths []string
+	if !isTrue(os.Getenv("GIT_CONFIG_NOSYSTEM")) {
+		if system := os.Getenv("GIT_CONFIG_SYSTEM"); system != "" {
+			paths = append(paths, system)
+		} else {
+			paths = append(paths, "/etc/gitconfig")
+		}
+	}
+
+	if global, ok := os.LookupEnv("GIT_CONFIG_GLOBAL"); ok {
+		if global != "" {
+			paths = append(paths, global)
+		}
+		return paths
+	}
+	home, _ := os.UserHomeDir()
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" && home != "" {
+		xdg = filepath.Join(home, ".config")
+	}
+	if xdg != "" {
+		paths = append(paths, filepath.Join(xdg, "git", "config"))
+	}
+	if home != "" {
+		paths = append(paths, filepath.Join(home, ".gitconfig"))
+	}
+	return paths
+}
+
+func isTrue(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "yes", "on", "1":
+		return true
+	}
+	return false
+}
+
+// configLoader reads config files into cfg, expanding include and includeIf
+// sections as they are encountered.
+type configLoader struct {
+	gitDir string
+	cfg    *config
+}
+
+func (l *configLoader) load(path string, depth int, optional bool) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("%s: include depth exceeds %d", path, maxIncludeDepth)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if optional && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	return parseConfig(string(data), func(e configEntry) error {
+		l.cfg.entries = append(l.cfg.entries, e)
+		if e.key != "path" {
+			return nil
+		}
+		switch {
+		case e.section == "include" && e.subsection == "":
+		case e.section == "includeif" && l.matches(e.subsection, path):
+		default:
+			return nil
+		}
+		return l.load(includePath(path, e.value), depth+1, true)
+	})
+}
+
+// includePath resolves an include path relative to the including file, with
+// ~/ expanded to the home directory.
+func includePath(from, path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return resolvePath(filepath.Dir(from), path)
+}
+
+// matches evaluates an includeIf condition. Only gitdir, gitdir/i, and
+// onbranch are supported; other conditions never match.
+func (l *configLoader) matches(condition, from string) bool {
+	kind, pattern, ok := strings.Cut(condition, ":")
+	if !ok {
+		return false
+	}
+
+	switch kind {
+	case "gitdir", "gitdir/i":
+		// Check the trailing slash before expanding: resolving ~/ and ./
+		// cleans it away.
+		recursive := strings.HasSuffix(pattern, "/")
+		switch {
+		case strings.HasPrefix(pattern, "~/"):
+			pattern = includePath(from, pattern)
+		case strings.HasPrefix(pattern, "./"):
+			pattern = filepath.Join(filepath.Dir(from), pattern)
+		case !filepath.IsAbs(pattern) && !strings.HasPrefix(pattern, "**"):
+			pattern = "**/" + pattern
+		}
+		if recursive {
+			pattern = strings.TrimSuffix(pattern, "/") + "/**"
+		}
+		fold := kind == "gitdir/i"
+		dir := filepath.ToSlash(l.gitDir)
+		if globMatch(filepath.ToSlash(pattern), dir, fold) {
+			return true
+		}
+		if real, err := filepath.EvalSymlinks(l.gitDir); err == nil {
+			return globMatch(filepath.ToSlash(pattern), filepath.ToSlash(real), fold)
+		}
+	case "onbranch":
+		branch := headBranch(l.gitDir)
+		if branch == "" {
+			return false
+		}
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "**"
+		}
+		return globMatch(pattern, branch, false)
+	}
+	return false
+}
+
+// headBranch returns the branch HEAD points at, or "" when it is detached.
+func headBranch(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	ref, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "ref: refs/heads/")
+	if !ok {
+		return ""
+	}
+	return ref
+}
+
+// globMatch matches name against a wildmatch pattern in which * and ? stop at
+// slashes and ** crosses them.
+func globMatch(pattern, name string, fold bool) bool {
+	var b strings.Builder
+	if fold {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return false
+	}
+	return re.MatchString(name)
+}
+
+// parseConfig parses git config syntax and calls emit for each entry in
+// order. It follows git's rules for sections (including the legacy
+// [section.subsection] form), quoting, escapes, comments, and line
+// continuations. A key without a value is reported with an empty value.
+func parseConfig(src string, emit func(configEntry) error) error {
+	p := &configParser{src: src, line: 1}
+	var section, subsection string
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil
+		}
+
+		switch c := p.peek(); {
+		case c == '#' || c == ';':
+			p.skipLine()
+		case c == '[':
+			var err error
+			section, subsection, err = p.header()
+			if err != nil {
+				return err
+			}
+		case isKeyStart(c):
+			if section == "" {
+				return p.errorf("key outside of a section")
+			}
+			key, value, err := p.entry()
+			if err != nil {
+				return err
+			}
+			if err := emit(configEntry{section: section, subsection: subsection, key: key, value: value}); err != nil {
+				return err
+			}
+		default:
+			return p.errorf("unexpected character %q", c)
+		}
+	}
+}
+
+type configParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *configParser) eof() bool  { return p.pos >= len(p.src) }
+func (p *configParser) peek() byte { return p.src[p.pos] }
+
+func (p *configParser) next() byte {
+	c := p.src[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+func (p *configParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("bad config line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *configParser) skipSpace() {
+	for !p.eof() && strings.IndexByte(" \t\r\n", p.peek()) >= 0 {
+		p.next()
+	}
+}
+
+func (p *configParser) skipBlanks() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t' || p.peek() == '\r') {
+		p.next()
+	}
+}
+
+func (p *configParser) skipLine() {
+	for !p.eof() && p.next() != '\n' {
+	}
+}
+
+func isKeyStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isKeyChar(c byte) bool {
+	return isKeyStart(c) || c >= '0' && c <= '9' || c == '-'
+}
+
+// header parses [section], [section "subsection"], or [section.subsection].
+func (p *configParser) header() (string, string, error) {
+	p.next() // [
+	start := p.pos
+	for !p.eof() && (isKeyChar(p.peek()) || p.peek() == '.') {
+		p.next()
+	}
+	name := strings.ToLower(p.src[start:p.pos])
+	if name == "" {
+		return "", "", p.errorf("empty section name")
+	}
+
+	if !p.eof() && p.peek() == ']' {
+		p.next()
+		if section, sub, ok := strings.Cut(name, "."); ok {
+			return section, sub, nil
+		}
+		return name, "", nil
+	}
+
+	p.skipBlanks()
+	if p.eof() || p.peek() != '"' {
+		return "", "", p.errorf("bad section header")
+	}
+	p.next()
+
+	var sub strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", "", p.errorf("unterminated subsection")
+		}
+		c := p.next()
+		if c == '"' {
+			break
+		}
+		if c == '\\' {
+			if p.eof() || p.peek() == '\n' {
+				return "", "", p.errorf("unterminated subsection")
+			}
+			c = p.next()
+		}
+		sub.WriteByte(c)
+	}
+	if p.eof() || p.next() != ']' {
+		return "", "", p.errorf("bad section header")
+	}
+	return name, sub.String(), nil
+}
+
+// entry parses "key = value" or a bare "key".
+func (p *configParser) entry() (string, string, error) {
+	start := p.pos
+	for !p.eof() && isKeyChar(p.peek()) {
+		p.next()
+	}
+	key := strings.ToLower(p.src[start:p.pos])
+
+	p.skipBlanks()
+	if p.eof() || p.peek() == '\n' {
+		return key, "", nil
+	}
+	switch p.peek() {
+	case '#', ';':
+		p.skipLine()
+		return key, "", nil
+	case '=':
+		p.next()
+	default:
+		return "", "", p.errorf("invalid key %q", key+string(p.peek()))
+	}
+
+	value, err := p.value()
+	return key, value, err
+}
+
+// value parses a value up to the end of the line, handling quotes, escapes,
+// comments, and backslash-newline continuations. Whitespace outside quotes
+// is kept between words but trimmed at both ends.
+func (p *configParser) value() (string, error) {
+	var b strings.Builder
+	quoted := false
+	spaces := 0
+	for !p.eof() {
+		c := p.next()
+		switch {
+		case c == '\n':
+			if quoted {
+				return "", p.errorf("unterminated quote")
+			}
+			return b.String(), nil
+		case !quoted && (c == '#' || c == ';'):
+			p.skipLine()
+			return b.String(), nil
+		case !quoted && (c == ' ' || c == '\t' || c == '\r'):
+			if b.Len() > 0 {
+				spaces++
+			}
+			continue
+		}
+
+		for ; spaces > 0; spaces-- {
+			b.WriteByte(' ')
+		}
+		switch c {
+		case '"':
+			quoted = !quoted
+		case '\\':
+			if p.eof() {
+				return "", p.errorf("bad escape")
+			}
+			switch e := p.next(); e {
+			case '\n':
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'b':
+				b.WriteByte('\b')
+			case '\\', '"':
+				b.WriteByte(e)
+			default:
+				return "", p.errorf("bad escape \\%c", e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if quoted {
+		return "", p.errorf("unterminated quote")
+	}
+	return b.String(), nil
+}
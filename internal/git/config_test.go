@@ -0,0 +1,302 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/arrno/bfast/internal/normalize"
+)
+
+// isolateGitConfig keeps the developer's system and global git config, and
+// their ssh config, out of a test, for both the git binary and loadConfig.
+func isolateGitConfig(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+}
+
+func TestParseConfigSyntax(t *testing.T) {
+	src := `# comment
+[core]
+	bare = false ; trailing comment
+	flag
+[remote "we\"ird"]
+	url = "https://github.com/a/b.git" # quoted
+[Remote.Legacy]
+	URL = git@github.com:c/d.git
+[alias]
+	long = log \
+		--oneline
+	spaced = one   two
+	escaped = "tab\there"
+`
+	var got []string
+	err := parseConfig(src, func(e configEntry) error {
+		got = append(got, e.section+"|"+e.subsection+"|"+e.key+"="+e.value)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("parseConfig returned error: %v", err)
+	}
+
+	want := []string{
+		"core||bare=false",
+		"core||flag=",
+		`remote|we"ird|url=https://github.com/a/b.git`,
+		"remote|legacy|url=git@github.com:c/d.git",
+		"alias||long=log   --oneline",
+		"alias||spaced=one   two",
+		"alias||escaped=tab\there",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("parseConfig entries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParseConfigRejectsMalformed(t *testing.T) {
+	for _, src := range []string{"key = outside", "[remote \"open\n", "[core]\n\tx = \"unterminated\n", "[core]\n\tx = bad\\q\n"} {
+		if err := parseConfig(src, func(configEntry) error { return nil }); err == nil {
+			t.Errorf("parseConfig(%q) succeeded, want error", src)
+		}
+	}
+}
+
+// TestReadRemotesMatchesGitRemote checks that reading .git/config picks the
+// same repository as parsing "git remote -v" for the parseRemotes cases.
+func TestReadRemotesMatchesGitRemote(t *testing.T) {
+	isolateGitConfig(t)
+	cases := [][][2]string{
+		{{"upstream", "https://github.com/other/repo.git"}, {"origin", "git@github.com:arrno/bfast.git"}},
+		{{"upstream", "https://github.com/foo/bar.git"}, {"another", "git@github.com:baz/qux.git"}},
+		{{"origin", "https://example.com/foo/bar.git"}},
+	}
+
+	for _, remotes := range cases {
+		root := initTestRepo(t)
+		for _, r := range remotes {
+			gitOutput(t, root, "remote", "add", r[0], r[1])
+		}
+
+		wantSlug, wantErr := parseRemotes(gitOutput(t, root, "remote", "-v"))
+
+		parsed, err := readRemotes(root)
+		if err != nil {
+			t.Fatalf("readRemotes returned error: %v", err)
+		}
+		var gotSlug normalize.Slug
+		d, gotErr := selectRemote(parsed, RemoteOptions{}, nil)
+		if d != nil {
+			gotSlug = d.Slug
+		}
+		if gotSlug != wantSlug || !sameError(gotErr, wantErr) {
+			t.Fatalf("remotes %v: got (%+v, %v), want (%+v, %v)", remotes, gotSlug, gotErr, wantSlug, wantErr)
+		}
+	}
+}
+
+func sameError(a, b error) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Error() == b.Error() && errors.Is(a, ErrAmbiguousRepo) == errors.Is(b, ErrAmbiguousRepo)
+}
+
+func TestReadRemotesFollowsGitdirAndCommondir(t *testing.T) {
+	isolateGitConfig(t)
+	base := t.TempDir()
+	main := filepath.Join(base, "main")
+	writeFile(t, mkdirs(t, filepath.Join(main, ".git"), "config"), "[remote \"origin\"]\n\turl = git@github.com:arrno/bfast.git\n")
+
+	// A linked worktree: .git is a file pointing into the main repository,
+	// whose commondir leads back to the shared config.
+	worktreeDir := filepath.Join(main, ".git", "worktrees", "wt")
+	writeFile(t, mkdirs(t, worktreeDir, "commondir"), "../..\n")
+	writeFile(t, mkdirs(t, filepath.Join(base, "wt"), ".git"), "gitdir: "+worktreeDir+"\n")
+
+	// A submodule: a relative gitdir with its own config.
+	writeFile(t, mkdirs(t, filepath.Join(main, ".git", "modules", "sub"), "config"), "[remote \"origin\"]\n\turl = https://github.com/arrno/sub\n")
+	writeFile(t, mkdirs(t, filepath.Join(main, "sub"), ".git"), "gitdir: ../.git/modules/sub\n")
+
+	for dir, want := range map[string]string{"wt": "arrno/bfast", "main/sub": "arrno/sub"} {
+		remotes, err := readRemotes(filepath.Join(base, dir))
+		if err != nil {
+			t.Fatalf("%s: readRemotes returned error: %v", dir, err)
+		}
+		d, err := selectRemote(remotes, RemoteOptions{}, nil)
+		if err != nil || d.Slug.String() != want {
+			t.Fatalf("%s: got (%v, %v), want %s", dir, d, err, want)
+		}
+	}
+}
+
+func TestLoadConfigFollowsIncludes(t *testing.T) {
+	isolateGitConfig(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	root := filepath.Join(home, "work", "proj")
+	gitDir := filepath.Join(root, ".git")
+
+	// The global config uses the home-relative and file-relative forms.
+	global := filepath.Join(home, ".gitconfig")
+	t.Setenv("GIT_CONFIG_GLOBAL", global)
+	writeFile(t, global, `[includeIf "gitdir:~/work/"]
+	path = ~/home.inc
+[includeIf "gitdir:./work/"]
+	path = dot.inc
+[includeIf "gitdir:~/play/"]
+	path = never.inc
+`)
+	writeFile(t, filepath.Join(home, "home.inc"), "[remote \"home\"]\n\turl = https://github.com/arrno/home\n")
+	writeFile(t, filepath.Join(home, "dot.inc"), "[remote \"dot\"]\n\turl = https://github.com/arrno/dot\n")
+
+	writeFile(t, mkdirs(t, gitDir, "HEAD"), "ref: refs/heads/main\n")
+	writeFile(t, filepath.Join(gitDir, "remotes.inc"), "[remote \"origin\"]\n\turl = https://github.com/arrno/bfast\n")
+	writeFile(t, filepath.Join(root, "work.inc"), "[remote \"work\"]\n\turl = https://github.com/arrno/work\n")
+	writeFile(t, filepath.Join(root, "branch.inc"), "[remote \"branch\"]\n\turl = https://github.com/arrno/branch\n")
+	writeFile(t, filepath.Join(root, "never.inc"), "[remote \"never\"]\n\turl = https://github.com/arrno/never\n")
+	writeFile(t, filepath.Join(gitDir, "config"), `[include]
+	path = remotes.inc
+[includeIf "gitdir:`+filepath.ToSlash(root)+`/"]
+	path = ../work.inc
+[includeIf "onbranch:ma*"]
+	path = ../branch.inc
+[includeIf "gitdir:/nowhere/"]
+	path = ../never.inc
+[includeIf "onbranch:release/"]
+	path = ../never.inc
+`)
+
+	cfg, err := loadConfig(root)
+	if err != nil {
+		t.Fatalf("loadConfig returned error: %v", err)
+	}
+	got := strings.Join(cfg.subsections("remote"), ",")
+	if got != "home,dot,origin,work,branch" {
+		t.Fatalf("remotes = %s, want home,dot,origin,work,branch", got)
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		fold, want    bool
+	}{
+		{"**/work/**", "/home/me/work/app/.git", false, true},
+		{"/home/me/work/**", "/home/me/play/app/.git", false, false},
+		{"/home/*/.git", "/home/me/.git", false, true},
+		{"/home/*/.git", "/home/me/x/.git", false, false},
+		{"/Home/ME/**", "/home/me/app/.git", true, true},
+		{"feature/**", "feature/a/b", false, true},
+		{"v[0-9]", "v1", false, true},
+	}
+	for _, tc := range cases {
+		if got := globMatch(tc.pattern, tc.name, tc.fold); got != tc.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", tc.pattern, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDetectRepoWithoutGitBinary(t *testing.T) {
+	isolateGitConfig(t)
+	root := t.TempDir()
+	writeFile(t, mkdirs(t, filepath.Join(root, ".git"), "config"), "[remote \"origin\"]\n\turl = git@github.com:arrno/bfast.git\n")
+	t.Setenv("PATH", t.TempDir())
+
+	d, err := DetectRepo(t.Context(), root, RemoteOptions{})
+	if err != nil {
+		t.Fatalf("DetectRepo returned error: %v", err)
+	}
+	if want := (normalize.Slug{Host: "github.com", Owner: "arrno", Repo: "bfast", Forge: normalize.GitHub}); d.Slug != want || d.Remote != "origin" {
+		t.Fatalf("DetectRepo = %+v, want %+v from origin", d, want)
+	}
+}
+
+// mkdirs creates dir and returns the path of name inside it.
+func mkdirs(t *testing.T, dir, name string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir %s: %v", dir, err)
+	}
+	return filepath.Join(dir, name)
+}
+
+// TestReadRemotesRewritesLikeGit compares the remote URLs read from the
+// config with "git remote -v" when insteadOf and pushInsteadOf rules apply.
+func TestReadRemotesRewritesLikeGit(t *testing.T) {
+	isolateGitConfig(t)
+	root := initTestRepo(t)
+	gitOutput(t, root, "config", "url.git@github.com:.insteadOf", "gh:")
+	gitOutput(t, root, "config", "--add", "url.https://github.com/.insteadOf", "https://mirror.example/")
+	gitOutput(t, root, "config", "url.https://github.com/arrno/.insteadOf", "gh:arrno/")
+	gitOutput(t, root, "config", "url.ssh://push.example/.pushInsteadOf", "https://mirror.example/")
+	gitOutput(t, root, "remote", "add", "origin", "gh:arrno/bfast.git")
+	gitOutput(t, root, "remote", "add", "mirror", "https://mirror.example/other/repo")
+	gitOutput(t, root, "remote", "add", "fork", "gh:someone/bfast.git")
+	gitOutput(t, root, "remote", "set-url", "--push", "fork", "gh:someone/push.git")
+
+	var want []string
+	for _, line := range strings.Split(strings.TrimSpace(gitOutput(t, root, "remote", "-v")), "\n") {
+		fields := strings.Fields(line)
+		want = append(want, fields[0]+" "+fields[1])
+	}
+
+	remotes, err := readRemotes(root)
+	if err != nil {
+		t.Fatalf("readRemotes returned error: %v", err)
+	}
+	var got []string
+	for _, r := range remotes {
+		got = append(got, r.name+" "+r.url)
+	}
+
+	sortStrings := func(s []string) string {
+		s = append([]string(nil), s...)
+		slices.Sort(s)
+		return strings.Join(s, "\n")
+	}
+	if sortStrings(got) != sortStrings(want) {
+		t.Fatalf("readRemotes:\n%s\ngit remote -v:\n%s", sortStrings(got), sortStrings(want))
+	}
+}
+
+func TestSelectRemoteResolvesSSHAliases(t *testing.T) {
+	sshDir := filepath.Join(t.TempDir(), ".ssh")
+	writeFile(t, mkdirs(t, sshDir, "config"), `Host github-work gh-*
+    HostName github.com
+    User git
+
+Include extra.conf
+
+Host *
+    HostName %h.internal.example
+`)
+	writeFile(t, filepath.Join(sshDir, "extra.conf"), "Host ghe\n  HostName = \"ssh.github.com\"\n")
+	hosts := &sshHosts{}
+	hosts.load(filepath.Join(sshDir, "config"), sshDir, 0)
+
+	cases := map[string]string{
+		"git@github-work:arrno/bfast.git":     "arrno/bfast",
+		"gh-personal:arrno/bfast":             "arrno/bfast",
+		"ssh://git@ghe:443/arrno/bfast.git":   "arrno/bfast",
+		"https://github.com/arrno/bfast.git":  "arrno/bfast",
+		"git@gitlab-alias:arrno/bfast.git":    "",
+		"git@github.com.evil:arrno/bfast.git": "",
+	}
+	for url, want := range cases {
+		d, err := selectRemote([]remote{{name: "origin", url: url}}, RemoteOptions{}, hosts)
+		if want == "" {
+			if err == nil {
+				t.Errorf("%s: got %v, want error", url, d.Slug)
+			}
+			continue
+		}
+		if err != nil || d.Slug.String() != want {
+			t.Errorf("%s: got (%v, %v), want %s", url, d, err, want)
+		}
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/arrno/bfast/internal/normalize"
@@ -16,10 +19,64 @@ import (
 // Errors returned while detecting repository information.
 var (
 	ErrNotRepository  = errors.New("not inside a git repository")
-	ErrNoGithubRemote = errors.New("could not infer GitHub repo. Use: bfast --repo owner/repo")
-	ErrAmbiguousRepo  = errors.New("multiple GitHub remotes detected. Use: bfast --repo owner/repo")
+	ErrNoRepoRemote   = errors.New("could not infer repo from the git remotes. Use: bfast --repo owner/repo")
+	ErrAmbiguousRepo  = errors.New("multiple repositories among the git remotes. Use: bfast --remote <name> or --repo owner/repo")
+	ErrRemoteNotFound = errors.New("no such git remote")
+	ErrDirtyIndex     = errors.New("git index has staged changes; commit or unstage them first")
+	ErrUnmergedFiles  = errors.New("repository has unmerged files; resolve conflicts first")
 )
 
+// DefaultRemotePreference is the remote order used when RemoteOptions does
+// not give one.
+var DefaultRemotePreference = []string{"origin"}
+
+// forkParentRemotes are remote names that conventionally point at the
+// repository a checkout was forked from.
+var forkParentRemotes = []string{"upstream"}
+
+// RemoteOptions controls which remote DetectRepo picks.
+type RemoteOptions struct {
+	// Name is the only remote considered when set.
+	Name string
+	// Preference lists remote names to try in order before falling back to
+	// the only repository among the remotes. Empty means
+	// DefaultRemotePreference.
+	Preference []string
+	// Hosts names self-hosted forges to recognize in remote URLs.
+	Hosts normalize.Hosts
+}
+
+// Detection is the repository picked from the remotes.
+type Detection struct {
+	Slug   normalize.Slug
+	Remote string
+	// Remotes maps each remote with a forge URL to its repository.
+	Remotes map[string]normalize.Slug
+}
+
+// LikelyForkOf applies a local heuristic: when the picked remote is not
+// upstream but an upstream remote points at a different repository, the
+// picked one is probably a fork of it.
+func (d *Detection) LikelyForkOf() (normalize.Slug, string, bool) {
+	for _, name := range forkParentRemotes {
+		parent, ok := d.Remotes[name]
+		if ok && d.Remote != name && parent != d.Slug {
+			return parent, name, true
+		}
+	}
+	return normalize.Slug{}, "", false
+}
+
+// AmbiguousRepoError lists the repositories found among the remotes
+// when none of them is preferred. It matches ErrAmbiguousRepo with errors.Is.
+type AmbiguousRepoError struct {
+	Candidates []string
+}
+
+func (e *AmbiguousRepoError) Error() string { return ErrAmbiguousRepo.Error() }
+
+func (e *AmbiguousRepoError) Is(target error) bool { return target == ErrAmbiguousRepo }
+
 // FindRepoRoot walks up from the provided directory until it finds a .git folder.
 func FindRepoRoot(start string) (string, error) {
 	dir, err := filepath.Abs(start)
@@ -29,6 +86,7 @@ func FindRepoRoot(start string) (string, error) {
 
 	for {
 		if hasGitDir(dir) {
+			slog.Debug("found git root", "start", start, "root", dir)
 			return dir, nil
 		}
 
@@ -47,10 +105,243 @@ func hasGitDir(dir string) bool {
 	return false
 }
 
-// DetectGithubSlug attempts to infer owner/repo from git remotes.
-func DetectGithubSlug(ctx context.Context, root string) (normalize.Slug, error) {
-	cmd := exec.CommandContext(ctx, "git", "remote", "-v")
-	cmd.Dir = root
+// DetectRepo infers owner/repo from the git remotes, picking among them as
+// opts says. The remotes are read from the repository's config files
+// directly, so no git binary is needed; "git remote -v" is only used when
+// they cannot be parsed.
+func DetectRepo(ctx context.Context, root string, opts RemoteOptions) (*Detection, error) {
+	remotes, err := readRemotes(root)
+	if err != nil {
+		slog.Debug("falling back to git remote -v", "root", root, "error", err)
+		out, err := run(ctx, root, "remote", "-v")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read git remotes: %w", err)
+		}
+		remotes = splitRemoteLines(out)
+	}
+
+	return selectRemote(remotes, opts, loadSSHHosts())
+}
+
+// remote is one remote URL, as a line of "git remote -v" shows it.
+type remote struct {
+	name string
+	url  string
+}
+
+// readRemotes lists the remotes in the git config of the repository at root
+// the way "git remote -v" does: the first url of each remote for fetch, and
+// its pushurls, or else all of its urls, for push. URLs are rewritten by
+// url.<base>.insteadOf and, for push urls, url.<base>.pushInsteadOf.
+func readRemotes(root string) ([]remote, error) {
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var remotes []remote
+	for _, name := range cfg.subsections("remote") {
+		urls := cfg.getAll("remote", name, "url")
+		if len(urls) == 0 {
+			continue
+		}
+		remotes = append(remotes, remote{name: name, url: rewriteURL(cfg, urls[0], "insteadof")})
+
+		if push := cfg.getAll("remote", name, "pushurl"); len(push) > 0 {
+			for _, u := range push {
+				remotes = append(remotes, remote{name: name, url: rewriteURL(cfg, u, "insteadof")})
+			}
+			continue
+		}
+		for _, u := range urls {
+			pushURL := rewriteURL(cfg, u, "pushinsteadof")
+			if pushURL == u {
+				pushURL = rewriteURL(cfg, u, "insteadof")
+			}
+			remotes = append(remotes, remote{name: name, url: pushURL})
+		}
+	}
+	return remotes, nil
+}
+
+// rewriteURL applies the url.<base>.<key> rule with the longest matching
+// prefix, as git does for insteadOf and pushInsteadOf.
+func rewriteURL(cfg *config, raw, key string) string {
+	best, bestPrefix := "", ""
+	for _, base := range cfg.subsections("url") {
+		for _, prefix := range cfg.getAll("url", base, key) {
+			if strings.HasPrefix(raw, prefix) && len(prefix) > len(bestPrefix) {
+				best, bestPrefix = base, prefix
+			}
+		}
+	}
+	if bestPrefix == "" {
+		return raw
+	}
+	rewritten := best + strings.TrimPrefix(raw, bestPrefix)
+	slog.Debug("rewrote remote url", "from", raw, "to", rewritten, "rule", "url."+best+"."+key)
+	return rewritten
+}
+
+// CheckIndexClean returns ErrUnmergedFiles when a merge is unresolved and
+// ErrDirtyIndex when anything is already staged, so a commit made by bfast
+// contains only its own change.
+func CheckIndexClean(ctx context.Context, root string) error {
+	unmerged, err := run(ctx, root, "ls-files", "--unmerged")
+	if err != nil {
+		return fmt.Errorf("failed to inspect git index: %w", err)
+	}
+	if strings.TrimSpace(unmerged) != "" {
+		return ErrUnmergedFiles
+	}
+
+	staged, err := run(ctx, root, "diff", "--cached", "--name-only")
+	if err != nil {
+		return fmt.Errorf("failed to inspect git index: %w", err)
+	}
+	if strings.TrimSpace(staged) != "" {
+		return ErrDirtyIndex
+	}
+	return nil
+}
+
+// CommitFile stages a single file and commits it, returning the new commit
+// hash. It runs plain "git commit", so the user's hooks and signing
+// configuration (commit.gpgsign, gpg.format, user.signingkey) apply.
+func CommitFile(ctx context.Context, root, path, message string) (string, error) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the repository at %s", path, root)
+	}
+
+	if _, err := run(ctx, root, "add", "--", rel); err != nil {
+		return "", fmt.Errorf("failed to stage %s: %w", rel, err)
+	}
+	if _, err := run(ctx, root, "commit", "--quiet", "-m", message); err != nil {
+		return "", fmt.Errorf("failed to commit %s: %w", rel, err)
+	}
+
+	hash, err := run(ctx, root, "rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit: %w", err)
+	}
+	return strings.TrimSpace(hash), nil
+}
+
+// CurrentBranch returns the checked-out branch name. It fails on a detached
+// HEAD.
+func CurrentBranch(ctx context.Context, root string) (string, error) {
+	out, err := run(ctx, root, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to determine current branch (detached HEAD?): %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// CreateBranch creates a new branch at HEAD and checks it out.
+func CreateBranch(ctx context.Context, root, name string) error {
+	if _, err := run(ctx, root, "checkout", "--quiet", "-b", name); err != nil {
+		return fmt.Errorf("failed to create branch %s: %w", name, err)
+	}
+	return nil
+}
+
+// BranchExists reports whether a local branch with the given name exists.
+func BranchExists(ctx context.Context, root, name string) (bool, error) {
+	_, err := run(ctx, root, "rev-parse", "--verify", "--quiet", "refs/heads/"+name)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up branch %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// DeleteBranch force-deletes a local branch.
+func DeleteBranch(ctx context.Context, root, name string) error {
+	if _, err := run(ctx, root, "branch", "--quiet", "-D", name); err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", name, err)
+	}
+	return nil
+}
+
+// Checkout switches to an existing branch.
+func Checkout(ctx context.Context, root, name string) error {
+	if _, err := run(ctx, root, "checkout", "--quiet", name); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", name, err)
+	}
+	return nil
+}
+
+// Push pushes branch to remote and sets it as the upstream.
+func Push(ctx context.Context, root, remote, branch string) error {
+	if _, err := run(ctx, root, "push", "--quiet", "--set-upstream", remote, branch); err != nil {
+		return fmt.Errorf("failed to push %s to %s: %w", branch, remote, err)
+	}
+	return nil
+}
+
+// PushFile publishes content at path on branch of remote without touching
+// the working tree, the index, or any local branch. The file is the only
+// one in a new parentless commit, so no local history is pushed with it.
+// The branch is overwritten if it exists. It returns the commit hash.
+func PushFile(ctx context.Context, root, remote, branch, path, content, message string) (string, error) {
+	blob, err := runWith(ctx, root, strings.NewReader(content), nil, "hash-object", "-w", "--stdin")
+	if err != nil {
+		return "", fmt.Errorf("failed to store %s: %w", path, err)
+	}
+
+	tmp, err := os.MkdirTemp("", "bfast-index-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(tmp, "index")}
+
+	entry := "100644," + strings.TrimSpace(blob) + "," + path
+	if _, err := runWith(ctx, root, nil, env, "update-index", "--add", "--cacheinfo", entry); err != nil {
+		return "", fmt.Errorf("failed to stage %s: %w", path, err)
+	}
+	tree, err := runWith(ctx, root, nil, env, "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("failed to write tree: %w", err)
+	}
+	commit, err := run(ctx, root, "commit-tree", strings.TrimSpace(tree), "-m", message)
+	if err != nil {
+		return "", fmt.Errorf("failed to commit %s: %w", path, err)
+	}
+	commit = strings.TrimSpace(commit)
+
+	if _, err := run(ctx, root, "push", "--quiet", "--force", remote, commit+":refs/heads/"+branch); err != nil {
+		return "", fmt.Errorf("failed to push %s to %s: %w", branch, remote, err)
+	}
+	return commit, nil
+}
+
+// DeleteRemoteBranch deletes branch on remote.
+func DeleteRemoteBranch(ctx context.Context, root, remote, branch string) error {
+	if _, err := run(ctx, root, "push", "--quiet", remote, "--delete", branch); err != nil {
+		return fmt.Errorf("failed to delete %s on %s: %w", branch, remote, err)
+	}
+	return nil
+}
+
+// run executes git in dir and returns stdout. Failures include git's stderr.
+func run(ctx context.Context, dir string, args ...string) (string, error) {
+	return runWith(ctx, dir, nil, nil, args...)
+}
+
+// runWith is run with stdin and extra environment variables.
+func runWith(ctx context.Context, dir string, stdin io.Reader, env []string, args ...string) (string, error) {
+	slog.Debug("running git", "dir", dir, "args", args)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = stdin
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
@@ -58,18 +349,30 @@ func DetectGithubSlug(ctx context.Context, root string) (normalize.Slug, error)
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return normalize.Slug{}, fmt.Errorf("failed to read git remotes: %w", err)
+		slog.Debug("git failed", "args", args, "error", err, "stderr", strings.TrimSpace(stderr.String()))
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
 	}
 
-	return parseRemotes(stdout.String())
+	return stdout.String(), nil
 }
 
+// parseRemotes picks the repository from "git remote -v" output with the
+// default preference.
 func parseRemotes(output string) (normalize.Slug, error) {
-	lines := strings.Split(strings.TrimSpace(output), "\n")
-	candidates := map[string]normalize.Slug{}
-	var originSlug *normalize.Slug
+	d, err := selectRemote(splitRemoteLines(output), RemoteOptions{}, nil)
+	if err != nil {
+		return normalize.Slug{}, err
+	}
+	return d.Slug, nil
+}
 
-	for _, line := range lines {
+// splitRemoteLines parses the lines of "git remote -v".
+func splitRemoteLines(output string) []remote {
+	var remotes []remote
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
@@ -77,45 +380,82 @@ func parseRemotes(output string) (normalize.Slug, error) {
 
 		fields := strings.Fields(line)
 		if len(fields) < 2 {
+			slog.Debug("skipping remote line", "line", line, "reason", "malformed")
 			continue
 		}
+		remotes = append(remotes, remote{name: fields[0], url: fields[1]})
+	}
+	return remotes
+}
 
-		name := fields[0]
-		remoteURL := fields[1]
+// selectRemote picks the remote named in opts, else the first remote of the
+// preference order with a forge URL, else the only repository among
+// the remotes. SSH host aliases are resolved through hosts first; nil hosts
+// resolve none.
+func selectRemote(remotes []remote, opts RemoteOptions, hosts *sshHosts) (*Detection, error) {
+	d := &Detection{Remotes: map[string]normalize.Slug{}}
+	names := map[string]bool{}
+	candidates := map[string]string{} // owner/repo -> first remote with it
 
-		slug, err := normalize.Parse(remoteURL)
+	for _, r := range remotes {
+		names[r.name] = true
+		remoteURL := resolveSSHAlias(r.url, hosts)
+
+		slug, err := opts.Hosts.Parse(remoteURL)
 		if err != nil {
+			slog.Debug("skipping remote", "name", r.name, "url", remoteURL, "reason", err)
 			continue
 		}
+		slog.Debug("parsed remote", "name", r.name, "url", remoteURL, "slug", slug.String())
 
-		key := slug.String()
-		if _, exists := candidates[key]; !exists {
-			candidates[key] = slug
+		if _, exists := d.Remotes[r.name]; !exists {
+			d.Remotes[r.name] = slug
 		}
-
-		if name == "origin" {
-			if originSlug == nil {
-				tmp := slug
-				originSlug = &tmp
-			}
+		if _, exists := candidates[slug.String()]; !exists {
+			candidates[slug.String()] = r.name
 		}
 	}
 
-	if originSlug != nil {
-		return *originSlug, nil
+	pick := func(name, reason string) *Detection {
+		slog.Debug("selected remote", "name", name, "reason", reason)
+		d.Remote = name
+		d.Slug = d.Remotes[name]
+		return d
 	}
 
-	if len(candidates) == 0 {
-		return normalize.Slug{}, ErrNoGithubRemote
+	if opts.Name != "" {
+		if !names[opts.Name] {
+			return nil, fmt.Errorf("%w %q", ErrRemoteNotFound, opts.Name)
+		}
+		if _, ok := d.Remotes[opts.Name]; !ok {
+			return nil, fmt.Errorf("remote %q has no forge URL: %w", opts.Name, ErrNoRepoRemote)
+		}
+		return pick(opts.Name, "requested"), nil
 	}
 
-	if len(candidates) > 1 {
-		return normalize.Slug{}, ErrAmbiguousRepo
+	preference := opts.Preference
+	if len(preference) == 0 {
+		preference = DefaultRemotePreference
+	}
+	for _, name := range preference {
+		if _, ok := d.Remotes[name]; ok {
+			return pick(name, "preferred"), nil
+		}
 	}
 
-	for _, slug := range candidates {
-		return slug, nil
+	switch len(candidates) {
+	case 0:
+		return nil, ErrNoRepoRemote
+	case 1:
+		for _, name := range candidates {
+			return pick(name, "only repository"), nil
+		}
 	}
 
-	return normalize.Slug{}, ErrNoGithubRemote
+	slugs := make([]string, 0, len(candidates))
+	for key := range candidates {
+		slugs = append(slugs, key)
+	}
+	sort.Strings(slugs)
+	return nil, &AmbiguousRepoError{Candidates: slugs}
 }
@@ -1,8 +1,12 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/arrno/bfast/internal/normalize"
@@ -46,7 +50,7 @@ func TestParseRemotesPrefersOrigin(t *testing.T) {
 		t.Fatalf("parseRemotes returned error: %v", err)
 	}
 
-	want := normalize.Slug{Owner: "arrno", Repo: "bfast"}
+	want := normalize.Slug{Host: "github.com", Owner: "arrno", Repo: "bfast", Forge: normalize.GitHub}
 	if slug != want {
 		t.Fatalf("parseRemotes = %+v, want %+v", slug, want)
 	}
@@ -56,13 +60,158 @@ func TestParseRemotesAmbiguous(t *testing.T) {
 	out := "upstream\thttps://github.com/foo/bar.git (fetch)\n" +
 		"another\tgit@github.com:baz/qux.git (fetch)\n"
 
-	if _, err := parseRemotes(out); err == nil || err != ErrAmbiguousRepo {
+	_, err := parseRemotes(out)
+	if !errors.Is(err, ErrAmbiguousRepo) {
 		t.Fatalf("expected ErrAmbiguousRepo, got %v", err)
 	}
+	var ambiguous *AmbiguousRepoError
+	if !errors.As(err, &ambiguous) || strings.Join(ambiguous.Candidates, ",") != "baz/qux,foo/bar" {
+		t.Fatalf("unexpected candidates: %+v", err)
+	}
+}
+
+func TestParseRemotesNoForge(t *testing.T) {
+	if _, err := parseRemotes("origin\thttps://example.com/foo/bar.git (fetch)\n"); err == nil || err != ErrNoRepoRemote {
+		t.Fatalf("expected ErrNoRepoRemote, got %v", err)
+	}
+}
+
+func TestCommitFileCommitsOnlyThatFile(t *testing.T) {
+	root := initTestRepo(t)
+	readme := filepath.Join(root, "README.md")
+	other := filepath.Join(root, "notes.txt")
+	writeFile(t, readme, "# Demo\n")
+	writeFile(t, other, "scratch\n")
+
+	ctx := context.Background()
+	if err := CheckIndexClean(ctx, root); err != nil {
+		t.Fatalf("CheckIndexClean on fresh repo: %v", err)
+	}
+
+	hash, err := CommitFile(ctx, root, readme, "Add badge")
+	if err != nil {
+		t.Fatalf("CommitFile returned error: %v", err)
+	}
+	if len(hash) < 7 {
+		t.Fatalf("unexpected hash %q", hash)
+	}
+
+	files := gitOutput(t, root, "show", "--name-only", "--format=%s", "HEAD")
+	if files != "Add badge\n\nREADME.md\n" {
+		t.Fatalf("unexpected commit contents: %q", files)
+	}
+}
+
+func TestCheckIndexCleanRejectsStagedChanges(t *testing.T) {
+	root := initTestRepo(t)
+	writeFile(t, filepath.Join(root, "staged.txt"), "x\n")
+	gitOutput(t, root, "add", "staged.txt")
+
+	if err := CheckIndexClean(context.Background(), root); !errors.Is(err, ErrDirtyIndex) {
+		t.Fatalf("expected ErrDirtyIndex, got %v", err)
+	}
+}
+
+func TestCommitFileRejectsPathOutsideRepo(t *testing.T) {
+	root := initTestRepo(t)
+	outside := filepath.Join(t.TempDir(), "README.md")
+	if _, err := CommitFile(context.Background(), root, outside, "x"); err == nil {
+		t.Fatal("expected error for path outside repository")
+	}
+}
+
+func initTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	gitOutput(t, root, "init", "--quiet")
+	gitOutput(t, root, "config", "user.name", "Test")
+	gitOutput(t, root, "config", "user.email", "test@example.com")
+	gitOutput(t, root, "config", "commit.gpgsign", "false")
+	return root
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return string(out)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
 }
 
-func TestParseRemotesNoGithub(t *testing.T) {
-	if _, err := parseRemotes("origin\thttps://gitlab.com/foo/bar.git (fetch)\n"); err == nil || err != ErrNoGithubRemote {
-		t.Fatalf("expected ErrNoGithubRemote, got %v", err)
+func TestSelectRemoteHonorsNameAndPreference(t *testing.T) {
+	remotes := []remote{
+		{name: "origin", url: "git@github.com:me/bfast.git"},
+		{name: "upstream", url: "https://github.com/arrno/bfast.git"},
+		{name: "gitlab", url: "https://gitlab.com/arrno/bfast.git"},
+		{name: "mirror", url: "https://example.com/arrno/bfast.git"},
+		{name: "team", url: "git@git.example.org:team/bfast.git"},
+	}
+	teamHosts := normalize.Hosts{"git.example.org": normalize.Gitea}
+
+	cases := []struct {
+		opts       RemoteOptions
+		wantRemote string
+		wantErr    error
+	}{
+		{RemoteOptions{}, "origin", nil},
+		{RemoteOptions{Name: "upstream"}, "upstream", nil},
+		{RemoteOptions{Preference: []string{"upstream", "origin"}}, "upstream", nil},
+		{RemoteOptions{Preference: []string{"missing", "origin"}}, "origin", nil},
+		{RemoteOptions{Name: "nope"}, "", ErrRemoteNotFound},
+		{RemoteOptions{Name: "gitlab"}, "gitlab", nil},
+		{RemoteOptions{Name: "mirror"}, "", ErrNoRepoRemote},
+		{RemoteOptions{Name: "team"}, "", ErrNoRepoRemote},
+		{RemoteOptions{Name: "team", Hosts: teamHosts}, "team", nil},
+		{RemoteOptions{Preference: []string{"mirror"}}, "", ErrAmbiguousRepo},
+	}
+
+	for _, tc := range cases {
+		d, err := selectRemote(remotes, tc.opts, nil)
+		if tc.wantErr != nil {
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("%+v: error = %v, want %v", tc.opts, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil || d.Remote != tc.wantRemote {
+			t.Errorf("%+v: got (%+v, %v), want remote %s", tc.opts, d, err, tc.wantRemote)
+		}
+	}
+}
+
+func TestDetectionLikelyForkOf(t *testing.T) {
+	remotes := []remote{
+		{name: "origin", url: "git@github.com:me/bfast.git"},
+		{name: "upstream", url: "https://github.com/arrno/bfast.git"},
+	}
+
+	d, err := selectRemote(remotes, RemoteOptions{}, nil)
+	if err != nil {
+		t.Fatalf("selectRemote returned error: %v", err)
+	}
+	parent, name, ok := d.LikelyForkOf()
+	if !ok || name != "upstream" || parent.String() != "arrno/bfast" {
+		t.Fatalf("LikelyForkOf = %v, %q, %v", parent, name, ok)
+	}
+
+	d, err = selectRemote(remotes, RemoteOptions{Name: "upstream"}, nil)
+	if err != nil {
+		t.Fatalf("selectRemote returned error: %v", err)
+	}
+	if _, _, ok := d.LikelyForkOf(); ok {
+		t.Fatal("upstream itself should not look like a fork")
 	}
 }
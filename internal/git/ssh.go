@@ -0,0 +1,212 @@
+package git
+
+import (
+	"bufio"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxSSHIncludeDepth bounds nested Include directives in ssh config files.
+const maxSSHIncludeDepth = 16
+
+// scpURL matches git's scp-like syntax, [user@]host:path. A one-letter host
+// is a Windows drive, not a host.
+var scpURL = regexp.MustCompile(`^(?:([^@/:]+)@)?([^@/:]{2,}):(.*)$`)
+
+// sshHosts resolves SSH host aliases to real host names the way ssh does,
+// from ~/.ssh/config and then /etc/ssh/ssh_config.
+type sshHosts struct {
+	blocks []sshBlock
+}
+
+// sshBlock is the HostName set under one Host line, or before the first
+// one. Blocks under Match lines are skipped, since their criteria cannot be
+// evaluated here.
+type sshBlock struct {
+	patterns []string // nil matches every host
+	hostName string
+}
+
+func loadSSHHosts() *sshHosts {
+	h := &sshHosts{}
+	if home, err := os.UserHomeDir(); err == nil {
+		h.load(filepath.Join(home, ".ssh", "config"), filepath.Join(home, ".ssh"), 0)
+	}
+	h.load("/etc/ssh/ssh_config", "/etc/ssh", 0)
+	return h
+}
+
+// load reads one ssh config file. Relative Include paths are resolved
+// against base. Unreadable files are ignored, as ssh ignores missing ones.
+func (h *sshHosts) load(path, base string, depth int) {
+	if depth > maxSSHIncludeDepth {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	current := &sshBlock{}
+	skip := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		keyword, args := splitSSHLine(scanner.Text())
+		switch keyword {
+		case "":
+		case "host":
+			h.blocks = append(h.blocks, *current)
+			current = &sshBlock{patterns: args}
+			skip = false
+		case "match":
+			h.blocks = append(h.blocks, *current)
+			current = &sshBlock{}
+			skip = true
+		case "hostname":
+			if !skip && current.hostName == "" && len(args) > 0 {
+				current.hostName = args[0]
+			}
+		case "include":
+			if skip {
+				continue
+			}
+			h.blocks = append(h.blocks, *current)
+			for _, pattern := range args {
+				if strings.HasPrefix(pattern, "~/") {
+					if home, err := os.UserHomeDir(); err == nil {
+						pattern = filepath.Join(home, pattern[2:])
+					}
+				}
+				matches, _ := filepath.Glob(resolvePath(base, pattern))
+				for _, match := range matches {
+					h.load(match, base, depth+1)
+				}
+			}
+			current = &sshBlock{patterns: current.patterns}
+		}
+	}
+	h.blocks = append(h.blocks, *current)
+}
+
+// splitSSHLine returns the lower-cased keyword and the arguments of an ssh
+// config line, which may separate them with whitespace or "=".
+func splitSSHLine(line string) (string, []string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil
+	}
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return strings.ToLower(line), nil
+	}
+	keyword := strings.ToLower(line[:end])
+	rest := strings.TrimLeft(strings.TrimSpace(line[end:]), "=")
+	return keyword, splitQuoted(rest)
+}
+
+// splitQuoted splits s on whitespace, keeping double-quoted runs together.
+func splitQuoted(s string) []string {
+	var fields []string
+	var b strings.Builder
+	quoted, inField := false, false
+	for _, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+			inField = true
+		case !quoted && (c == ' ' || c == '\t'):
+			if inField {
+				fields = append(fields, b.String())
+				b.Reset()
+				inField = false
+			}
+		default:
+			b.WriteRune(c)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, b.String())
+	}
+	return fields
+}
+
+// hostName returns the HostName configured for alias, or alias itself when
+// there is none. Like ssh, the first matching value wins.
+func (h *sshHosts) hostName(alias string) string {
+	if h == nil {
+		return alias
+	}
+	for _, block := range h.blocks {
+		if block.hostName == "" || !matchSSHPatterns(block.patterns, alias) {
+			continue
+		}
+		return strings.ReplaceAll(block.hostName, "%h", alias)
+	}
+	return alias
+}
+
+// matchSSHPatterns reports whether host matches a Host line: any positive
+// pattern matches and no negated one does.
+func matchSSHPatterns(patterns []string, host string) bool {
+	if patterns == nil {
+		return true
+	}
+	matched := false
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		if ok, _ := filepath.Match(strings.ToLower(strings.TrimPrefix(pattern, "!")), strings.ToLower(host)); ok {
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// resolveSSHAlias rewrites the host of an SSH remote URL, in ssh:// or
+// scp-like form, to the HostName its ssh config gives it. Other URLs are
+// returned unchanged.
+func resolveSSHAlias(raw string, hosts *sshHosts) string {
+	if strings.HasPrefix(raw, "ssh://") || strings.HasPrefix(raw, "git+ssh://") {
+		parsed, err := url.Parse(raw)
+		if err != nil {
+			return raw
+		}
+		alias := parsed.Hostname()
+		real := hosts.hostName(alias)
+		if real == alias {
+			return raw
+		}
+		if port := parsed.Port(); port != "" {
+			parsed.Host = real + ":" + port
+		} else {
+			parsed.Host = real
+		}
+		slog.Debug("resolved ssh host alias", "alias", alias, "host", real)
+		return parsed.String()
+	}
+
+	if strings.Contains(raw, "://") {
+		return raw
+	}
+	m := scpURL.FindStringSubmatch(raw)
+	if m == nil {
+		return raw
+	}
+	real := hosts.hostName(m[2])
+	if real == m[2] {
+		return raw
+	}
+	slog.Debug("resolved ssh host alias", "alias", m[2], "host", real)
+	if m[1] != "" {
+		return m[1] + "@" + real + ":" + m[3]
+	}
+	return real + ":" + m[3]
+}
@@ -0,0 +1,159 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the public GitHub REST API.
+const DefaultBaseURL = "https://api.github.com"
+
+// Client talks to a GitHub-compatible REST API.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewPullRequest holds the fields needed to open a pull request.
+type NewPullRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Head  string `json:"head"`
+	Base  string `json:"base"`
+}
+
+// PullRequest is a subset of the API's pull request payload.
+type PullRequest struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+}
+
+// Error represents a non-2xx response from the API.
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("github request failed with status %d", e.Status)
+	}
+	return fmt.Sprintf("github request failed: %s (status %d)", e.Message, e.Status)
+}
+
+// NewClient builds a client for baseURL, defaulting to DefaultBaseURL.
+func NewClient(baseURL, token string, httpClient *http.Client) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), token: token, httpClient: httpClient}
+}
+
+// Repository is a subset of the API's repository payload.
+type Repository struct {
+	FullName string      `json:"full_name"`
+	Fork     bool        `json:"fork"`
+	Parent   *Repository `json:"parent,omitempty"`
+}
+
+// CreatePullRequest opens a pull request on owner/repo.
+func (c *Client) CreatePullRequest(ctx context.Context, owner, repo string, pr NewPullRequest) (*PullRequest, error) {
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(pr); err != nil {
+		return nil, err
+	}
+
+	data, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/pulls", owner, repo), buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var created PullRequest
+	if err := json.Unmarshal(data, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// GetRepository fetches owner/repo, including whether it is a fork and of
+// what. It works without a token for public repositories.
+func (c *Client) GetRepository(ctx context.Context, owner, repo string) (*Repository, error) {
+	data, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/%s", owner, repo), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var repository Repository
+	if err := json.Unmarshal(data, &repository); err != nil {
+		return nil, err
+	}
+	return &repository, nil
+}
+
+// do sends a request and returns the body of a 2xx response, or an *Error.
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", "bfast-cli")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	slog.Debug("github request", "method", req.Method, "url", req.URL.String())
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Debug("github response", "url", req.URL.String(), "status", resp.StatusCode)
+	if resp.StatusCode >= 300 {
+		return nil, &Error{Status: resp.StatusCode, Message: extractMessage(data)}
+	}
+	return data, nil
+}
+
+// extractMessage pulls the message and any validation errors out of a GitHub
+// error payload.
+func extractMessage(body []byte) string {
+	var payload struct {
+		Message string `json:"message"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return strings.TrimSpace(string(body))
+	}
+
+	parts := []string{payload.Message}
+	for _, e := range payload.Errors {
+		if e.Message != "" {
+			parts = append(parts, e.Message)
+		}
+	}
+	return strings.Join(parts, ": ")
+}
@@ -0,0 +1,251 @@
+// Package har records HTTP traffic to a HAR 1.2 file with credentials
+// redacted, so the file is safe to attach to bug reports.
+package har
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Redacted replaces every secret value in a recording.
+const Redacted = "[REDACTED]"
+
+// sensitiveHeaders are always redacted, whatever their value.
+var sensitiveHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+	"x-api-key":           true,
+}
+
+// sensitiveParams are query parameters redacted by name.
+var sensitiveParams = []string{"token", "access_token", "api_key", "password"}
+
+// Recorder is an http.RoundTripper that records each exchange before
+// returning it. It is safe for concurrent use.
+type Recorder struct {
+	next    http.RoundTripper
+	mu      sync.Mutex
+	entries []Entry
+	secrets []string
+}
+
+// NewRecorder wraps next, or http.DefaultTransport when next is nil.
+func NewRecorder(next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{next: next}
+}
+
+// AddSecret registers values to scrub from URLs and bodies when the file is
+// written. Empty values are ignored.
+func (r *Recorder) AddSecret(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			r.secrets = append(r.secrets, v)
+		}
+	}
+}
+
+// RoundTrip sends req through the wrapped transport and records the request,
+// the response or error, and the elapsed time.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r.record(r.next, req)
+}
+
+// Wrap returns a RoundTripper that sends requests through next, or
+// http.DefaultTransport when next is nil, and records them into r. It lets
+// clients with different transports share one recording.
+func (r *Recorder) Wrap(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return r.record(next, req)
+	})
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func (r *Recorder) record(next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	reqBody, err := drainRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, rtErr := next.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	entry := Entry{
+		StartedDateTime: start.UTC().Format(time.RFC3339Nano),
+		Time:            millis(elapsed),
+		Request:         newRequest(req, reqBody),
+		Timings:         Timings{Send: 0, Wait: millis(elapsed), Receive: 0},
+	}
+
+	if rtErr != nil {
+		entry.Error = rtErr.Error()
+		r.add(entry)
+		return nil, rtErr
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	entry.Response = newResponse(resp, respBody)
+	r.add(entry)
+	return resp, nil
+}
+
+func (r *Recorder) add(entry Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// WriteFile writes the recording made so far to path as HAR JSON.
+func (r *Recorder) WriteFile(path string) error {
+	r.mu.Lock()
+	doc := Document{Log: Log{
+		Version: "1.2",
+		Creator: Creator{Name: "bfast", Version: "dev"},
+		Entries: make([]Entry, len(r.entries)),
+	}}
+	copy(doc.Log.Entries, r.entries)
+	secrets := append([]string(nil), r.secrets...)
+	r.mu.Unlock()
+
+	if doc.Log.Entries == nil {
+		doc.Log.Entries = []Entry{}
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = []byte(scrub(string(data), secrets))
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write HTTP trace: %w", err)
+	}
+	return nil
+}
+
+// drainRequest reads the request body and replaces it so the transport can
+// still send it.
+func drainRequest(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func newRequest(req *http.Request, body []byte) Request {
+	u := *req.URL
+	query := u.Query()
+	for _, name := range sensitiveParams {
+		if query.Has(name) {
+			query.Set(name, Redacted)
+			u.RawQuery = query.Encode()
+		}
+	}
+
+	out := Request{
+		Method:      req.Method,
+		URL:         u.String(),
+		HTTPVersion: req.Proto,
+		Headers:     headerList(req.Header),
+		QueryString: []NameValue{},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	for _, name := range sortedKeys(query) {
+		for _, v := range query[name] {
+			out.QueryString = append(out.QueryString, NameValue{Name: name, Value: v})
+		}
+	}
+	if body != nil {
+		out.PostData = &PostData{MimeType: req.Header.Get("Content-Type"), Text: string(body)}
+	}
+	return out
+}
+
+func newResponse(resp *http.Response, body []byte) *Response {
+	return &Response{
+		Status:      resp.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprint(resp.StatusCode))),
+		HTTPVersion: resp.Proto,
+		Headers:     headerList(resp.Header),
+		Content: Content{
+			Size:     len(body),
+			MimeType: resp.Header.Get("Content-Type"),
+			Text:     string(body),
+		},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+}
+
+func headerList(h http.Header) []NameValue {
+	list := []NameValue{}
+	for _, name := range sortedKeys(h) {
+		for _, v := range h[name] {
+			if sensitiveHeaders[strings.ToLower(name)] {
+				v = Redacted
+			}
+			list = append(list, NameValue{Name: name, Value: v})
+		}
+	}
+	return list
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// scrub replaces each secret in s, longest first so a secret containing
+// another is not partially revealed.
+func scrub(s string, secrets []string) string {
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+		if quoted, err := json.Marshal(secret); err == nil {
+			inner := string(quoted[1 : len(quoted)-1])
+			s = strings.ReplaceAll(s, inner, Redacted)
+		}
+	}
+	return s
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
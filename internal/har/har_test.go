@@ -0,0 +1,106 @@
+package har
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorderWritesRedactedHAR(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"blurb":"fast"}` {
+			t.Errorf("server saw body %q", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"1","echo":"s3cret-token"}`))
+	}))
+	defer srv.Close()
+
+	rec := NewRecorder(nil)
+	rec.AddSecret("s3cret-token", "")
+	client := &http.Client{Transport: rec}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/project?repoUrl=x&token=abc", strings.NewReader(`{"blurb":"fast"}`))
+	req.Header.Set("Authorization", "Bearer s3cret-token")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `"id":"1"`) {
+		t.Fatalf("caller should still read the response body, got %q", body)
+	}
+
+	path := filepath.Join(t.TempDir(), "trace.har")
+	if err := rec.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read trace: %v", err)
+	}
+	if strings.Contains(string(data), "s3cret-token") || strings.Contains(string(data), "session=abc") || strings.Contains(string(data), "token=abc") {
+		t.Fatalf("trace leaks a secret:\n%s", data)
+	}
+
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("decode trace: %v", err)
+	}
+	if len(doc.Log.Entries) != 1 {
+		t.Fatalf("entries = %d, want 1", len(doc.Log.Entries))
+	}
+	entry := doc.Log.Entries[0]
+	if entry.Request.Method != http.MethodPost || entry.Request.PostData == nil || entry.Request.PostData.Text != `{"blurb":"fast"}` {
+		t.Fatalf("unexpected request: %+v", entry.Request)
+	}
+	if entry.Response == nil || entry.Response.Status != http.StatusCreated || entry.Response.Content.MimeType != "application/json" {
+		t.Fatalf("unexpected response: %+v", entry.Response)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat trace: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("trace mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestRecorderRecordsTransportErrors(t *testing.T) {
+	rec := NewRecorder(failingTransport{})
+	client := &http.Client{Transport: rec}
+	if _, err := client.Get("http://example.invalid/api/project"); err == nil {
+		t.Fatal("expected transport error")
+	}
+
+	path := filepath.Join(t.TempDir(), "trace.har")
+	if err := rec.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("decode trace: %v", err)
+	}
+	if len(doc.Log.Entries) != 1 || doc.Log.Entries[0].Response != nil || doc.Log.Entries[0].Error != "connection refused" {
+		t.Fatalf("unexpected entries: %+v", doc.Log.Entries)
+	}
+}
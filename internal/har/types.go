@@ -0,0 +1,85 @@
+package har
+
+// The types below follow the HAR 1.2 format
+// (http://www.softwareishard.com/blog/har-12-spec/), limited to the fields
+// bfast fills in.
+
+// Document is the top-level HAR object.
+type Document struct {
+	Log Log `json:"log"`
+}
+
+// Log holds the recorded entries.
+type Log struct {
+	Version string  `json:"version"`
+	Creator Creator `json:"creator"`
+	Entries []Entry `json:"entries"`
+}
+
+// Creator names the tool that wrote the file.
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Entry is one request and its response. Error is set instead of a response
+// when the request failed in transport.
+type Entry struct {
+	StartedDateTime string    `json:"startedDateTime"`
+	Time            float64   `json:"time"`
+	Request         Request   `json:"request"`
+	Response        *Response `json:"response,omitempty"`
+	Cache           struct{}  `json:"cache"`
+	Timings         Timings   `json:"timings"`
+	Error           string    `json:"_error,omitempty"`
+}
+
+// Request is the recorded request.
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	PostData    *PostData   `json:"postData,omitempty"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+// Response is the recorded response.
+type Response struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []NameValue `json:"headers"`
+	Content     Content     `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+// NameValue is a header or query parameter.
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PostData is a request body.
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// Content is a response body.
+type Content struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// Timings splits the entry time. bfast only measures the total wait.
+type Timings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
@@ -8,21 +8,123 @@ import (
 	"strings"
 )
 
-// Slug represents a GitHub repository in owner/repo form.
+// Forge is the kind of code host serving a repository. It decides which URL
+// shapes are understood and how the repository path is read from them.
+type Forge string
+
+// Supported forges. Gitea also covers Forgejo and Codeberg.
+const (
+	GitHub    Forge = "github"
+	GitLab    Forge = "gitlab"
+	Gitea     Forge = "gitea"
+	Bitbucket Forge = "bitbucket"
+)
+
+// Slug represents a repository on a code host. Owner is the namespace the
+// repository lives in; on GitLab it may span nested subgroups, as in
+// "group/subgroup".
 type Slug struct {
+	Host  string
 	Owner string
 	Repo  string
+	Forge Forge
 }
 
 // ErrInvalidRepo indicates that the provided repository reference could not be parsed.
-var ErrInvalidRepo = errors.New("invalid GitHub repository reference")
+var ErrInvalidRepo = errors.New("invalid repository reference")
+
+// githubHost is the host assumed for bare owner/repo references.
+const githubHost = "github.com"
+
+// knownHosts maps the public forges' host names to their forge.
+var knownHosts = Hosts{
+	"github.com":    GitHub,
+	"gitlab.com":    GitLab,
+	"codeberg.org":  Gitea,
+	"gitea.com":     Gitea,
+	"bitbucket.org": Bitbucket,
+}
+
+// hostAliases maps alternative host names, such as the SSH-over-HTTPS-port
+// endpoints, to the host used in canonical URLs.
+var hostAliases = map[string]string{
+	"www.github.com":       "github.com",
+	"ssh.github.com":       "github.com",
+	"www.gitlab.com":       "gitlab.com",
+	"altssh.gitlab.com":    "gitlab.com",
+	"www.bitbucket.org":    "bitbucket.org",
+	"altssh.bitbucket.org": "bitbucket.org",
+}
+
+// hostPrefixes recognize self-hosted instances by the first label of their
+// host name, such as gitlab.example.com.
+var hostPrefixes = map[string]Forge{
+	"gitlab":  GitLab,
+	"gitea":   Gitea,
+	"forgejo": Gitea,
+}
+
+// forgeNames are the names accepted by ParseForge.
+var forgeNames = map[string]Forge{
+	"github":    GitHub,
+	"gitlab":    GitLab,
+	"gitea":     Gitea,
+	"forgejo":   Gitea,
+	"codeberg":  Gitea,
+	"bitbucket": Bitbucket,
+}
+
+// maxGitLabDepth bounds the namespace nesting GitLab allows.
+const maxGitLabDepth = 20
 
 var slugPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
 var partPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
 
+// Hosts maps self-hosted host names to the forge they run, on top of the
+// public forges.
+type Hosts map[string]Forge
+
+// ParseForge resolves a forge name, case-insensitively.
+func ParseForge(name string) (Forge, error) {
+	if forge, ok := forgeNames[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return forge, nil
+	}
+	return "", fmt.Errorf("unknown forge %q (use github, gitlab, gitea, or bitbucket)", name)
+}
+
+// ParseHosts reads a comma-separated list of host=forge pairs, such as
+// "git.example.com=gitea,code.example.com=gitlab".
+func ParseHosts(spec string) (Hosts, error) {
+	hosts := Hosts{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		host, name, ok := strings.Cut(pair, "=")
+		host = strings.ToLower(strings.TrimSpace(host))
+		if !ok || host == "" {
+			return nil, fmt.Errorf("invalid forge host %q (use host=forge)", pair)
+		}
+		forge, err := ParseForge(name)
+		if err != nil {
+			return nil, err
+		}
+		hosts[host] = forge
+	}
+	return hosts, nil
+}
+
 // Parse attempts to build a slug from a variety of user inputs, including
-// owner/repo strings, HTTPS URLs, SSH URLs, and remote declarations.
+// owner/repo strings, host/path strings, HTTPS URLs, SSH URLs, and remote
+// declarations, recognizing only the public forges and self-hosted hosts
+// named like gitlab.example.com.
 func Parse(input string) (Slug, error) {
+	return Hosts(nil).Parse(input)
+}
+
+// Parse is like the package-level Parse, but also recognizes the hosts in h.
+func (h Hosts) Parse(input string) (Slug, error) {
 	trimmed := strings.TrimSpace(input)
 	if trimmed == "" {
 		return Slug{}, ErrInvalidRepo
@@ -32,72 +134,95 @@ func Parse(input string) (Slug, error) {
 
 	if slugPattern.MatchString(trimmed) {
 		parts := strings.SplitN(trimmed, "/", 2)
-		return newSlug(parts[0], parts[1])
+		return newSlug(githubHost, GitHub, parts[:1], parts[1])
 	}
 
-	if slug, ok := fromURL(trimmed); ok {
+	if slug, ok := h.fromHostPath(trimmed); ok {
 		return slug, nil
 	}
 
-	if slug, ok := fromSCP(trimmed); ok {
+	if slug, ok := h.fromURL(trimmed); ok {
+		return slug, nil
+	}
+
+	if slug, ok := h.fromSCP(trimmed); ok {
 		return slug, nil
 	}
 
 	return Slug{}, ErrInvalidRepo
 }
 
-// Format returns owner/repo string.
+// String returns owner/repo for github.com and host/owner/repo elsewhere, the
+// form accepted back by Parse.
 func (s Slug) String() string {
-	return fmt.Sprintf("%s/%s", s.Owner, s.Repo)
+	if s.Host == githubHost {
+		return fmt.Sprintf("%s/%s", s.Owner, s.Repo)
+	}
+	return fmt.Sprintf("%s/%s/%s", s.Host, s.Owner, s.Repo)
 }
 
-// RepoURL returns the canonical GitHub URL for the slug.
+// RepoURL returns the canonical web URL for the slug.
 func (s Slug) RepoURL() string {
-	return fmt.Sprintf("https://github.com/%s/%s", s.Owner, s.Repo)
+	return fmt.Sprintf("https://%s/%s/%s", s.Host, s.Owner, s.Repo)
 }
 
-// Encoded returns the owner/repo string URL encoded for use in query params.
+// Encoded returns the slug string URL encoded for use in query params.
 func (s Slug) Encoded() string {
 	return url.QueryEscape(s.String())
 }
 
-func newSlug(owner, repo string) (Slug, error) {
-	owner = strings.TrimSpace(owner)
-	repo = strings.TrimSpace(repo)
-	repo = strings.TrimSuffix(repo, ".git")
-	if owner == "" || repo == "" {
-		return Slug{}, ErrInvalidRepo
+// forge resolves a host name to its canonical form and forge.
+func (h Hosts) forge(host string) (string, Forge, bool) {
+	host = strings.ToLower(host)
+	if forge, ok := h[host]; ok {
+		return host, forge, true
 	}
-
-	if !partPattern.MatchString(owner) || !partPattern.MatchString(repo) {
-		return Slug{}, ErrInvalidRepo
+	if canonical, ok := hostAliases[host]; ok {
+		host = canonical
 	}
-
-	return Slug{Owner: owner, Repo: repo}, nil
-}
-
-func fromURL(raw string) (Slug, bool) {
-	parsed, err := url.Parse(raw)
-	if err != nil || parsed.Host == "" {
-		return Slug{}, false
+	if forge, ok := knownHosts[host]; ok {
+		return host, forge, true
 	}
+	label, _, ok := strings.Cut(host, ".")
+	if forge, known := hostPrefixes[label]; ok && known {
+		return host, forge, true
+	}
+	return "", "", false
+}
 
-	host := strings.ToLower(parsed.Host)
-	if host != "github.com" {
+// fromPath reads the repository from the path of a URL on host. GitLab
+// paths run up to the "-" separator of its web routes; the other forges use
+// the first two segments and ignore the rest, such as /tree/main.
+func (h Hosts) fromPath(host, path string) (Slug, bool) {
+	host, forge, ok := h.forge(host)
+	if !ok {
 		return Slug{}, false
 	}
 
-	path := strings.Trim(parsed.Path, "/")
+	path = strings.Trim(path, "/")
 	if path == "" {
 		return Slug{}, false
 	}
 
 	segments := strings.Split(path, "/")
+	if forge == GitLab {
+		for i, segment := range segments {
+			if segment == "-" {
+				segments = segments[:i]
+				break
+			}
+		}
+		if len(segments) > maxGitLabDepth+1 {
+			return Slug{}, false
+		}
+	} else if len(segments) > 2 {
+		segments = segments[:2]
+	}
 	if len(segments) < 2 {
 		return Slug{}, false
 	}
 
-	slug, err := newSlug(segments[0], segments[1])
+	slug, err := newSlug(host, forge, segments[:len(segments)-1], segments[len(segments)-1])
 	if err != nil {
 		return Slug{}, false
 	}
@@ -105,34 +230,59 @@ func fromURL(raw string) (Slug, bool) {
 	return slug, true
 }
 
-func fromSCP(raw string) (Slug, bool) {
+// fromHostPath handles host/owner/repo references without a scheme, such as
+// gitlab.com/group/subgroup/project.
+func (h Hosts) fromHostPath(raw string) (Slug, bool) {
+	host, path, ok := strings.Cut(raw, "/")
+	if !ok || !strings.Contains(host, ".") || strings.ContainsAny(host, ":@") {
+		return Slug{}, false
+	}
+	return h.fromPath(host, path)
+}
+
+func (h Hosts) fromURL(raw string) (Slug, bool) {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return Slug{}, false
+	}
+
+	return h.fromPath(parsed.Hostname(), parsed.Path)
+}
+
+func (h Hosts) fromSCP(raw string) (Slug, bool) {
 	if !strings.Contains(raw, ":") {
 		return Slug{}, false
 	}
 
 	parts := strings.SplitN(raw, ":", 2)
 	host := parts[0]
-	path := parts[1]
 
-	if !strings.Contains(host, "github.com") {
-		return Slug{}, false
+	// Drop the user in user@host. Aliases such as github-work must already
+	// be resolved to their real host name by the caller.
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		host = host[at+1:]
 	}
 
-	path = strings.TrimPrefix(path, "/")
-	path = strings.TrimSuffix(path, "/")
-	if path == "" {
-		return Slug{}, false
-	}
+	return h.fromPath(host, parts[1])
+}
 
-	segments := strings.Split(path, "/")
-	if len(segments) < 2 {
-		return Slug{}, false
+func newSlug(host string, forge Forge, namespace []string, repo string) (Slug, error) {
+	repo = strings.TrimSpace(repo)
+	repo = strings.TrimSuffix(repo, ".git")
+	if len(namespace) == 0 || !validPart(repo) {
+		return Slug{}, ErrInvalidRepo
 	}
 
-	slug, err := newSlug(segments[0], segments[1])
-	if err != nil {
-		return Slug{}, false
+	for i, part := range namespace {
+		namespace[i] = strings.TrimSpace(part)
+		if !validPart(namespace[i]) {
+			return Slug{}, ErrInvalidRepo
+		}
 	}
 
-	return slug, true
+	return Slug{Host: host, Owner: strings.Join(namespace, "/"), Repo: repo, Forge: forge}, nil
+}
+
+func validPart(part string) bool {
+	return part != "." && part != ".." && partPattern.MatchString(part)
 }
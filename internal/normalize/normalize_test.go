@@ -37,3 +37,109 @@ func TestParseInvalid(t *testing.T) {
 		t.Fatal("expected error for invalid slug")
 	}
 }
+
+func TestParseGithubHostsOnly(t *testing.T) {
+	for _, input := range []string{
+		"ssh://git@ssh.github.com:443/arrno/bfast.git",
+		"https://www.github.com/arrno/bfast",
+		"git@GitHub.com:arrno/bfast.git",
+	} {
+		slug, err := Parse(input)
+		if err != nil || slug.String() != "arrno/bfast" {
+			t.Errorf("Parse(%q) = %v, %v; want arrno/bfast", input, slug, err)
+		}
+	}
+
+	for _, input := range []string{
+		"git@github-work:arrno/bfast.git",
+		"git@notgithub.com:arrno/bfast.git",
+		"git@github.com.evil.example:arrno/bfast.git",
+		"gh:arrno/bfast",
+	} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", input)
+		}
+	}
+}
+
+func TestParseForgeURLShapes(t *testing.T) {
+	cases := []struct {
+		input string
+		want  Slug
+	}{
+		{"https://gitlab.com/group/sub/project.git", Slug{"gitlab.com", "group/sub", "project", GitLab}},
+		{"git@gitlab.com:group/sub/deeper/project.git", Slug{"gitlab.com", "group/sub/deeper", "project", GitLab}},
+		{"https://gitlab.com/group/project/-/merge_requests/1", Slug{"gitlab.com", "group", "project", GitLab}},
+		{"ssh://git@altssh.gitlab.com:443/group/project.git", Slug{"gitlab.com", "group", "project", GitLab}},
+		{"gitlab.com/group/sub/project", Slug{"gitlab.com", "group/sub", "project", GitLab}},
+		{"ssh://git@gitlab.example.com:2222/team/app.git", Slug{"gitlab.example.com", "team", "app", GitLab}},
+		{"https://codeberg.org/owner/repo/src/branch/main", Slug{"codeberg.org", "owner", "repo", Gitea}},
+		{"git@codeberg.org:owner/repo.git", Slug{"codeberg.org", "owner", "repo", Gitea}},
+		{"https://gitea.example.com/owner/repo.git", Slug{"gitea.example.com", "owner", "repo", Gitea}},
+		{"https://user@bitbucket.org/workspace/repo.git", Slug{"bitbucket.org", "workspace", "repo", Bitbucket}},
+		{"git@bitbucket.org:workspace/repo.git", Slug{"bitbucket.org", "workspace", "repo", Bitbucket}},
+		{"github.com/arrno/bfast", Slug{"github.com", "arrno", "bfast", GitHub}},
+	}
+	for _, tc := range cases {
+		got, err := Parse(tc.input)
+		if err != nil || got != tc.want {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v", tc.input, got, err, tc.want)
+		}
+	}
+
+	for _, input := range []string{
+		"https://git.example.com/owner/repo",
+		"https://gitlab.com/project",
+		"https://codeberg.org/owner/../repo",
+		"group/sub/project",
+	} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", input)
+		}
+	}
+}
+
+func TestSlugCanonicalForms(t *testing.T) {
+	cases := []struct {
+		input, str, url, encoded string
+	}{
+		{"git@github.com:arrno/bfast.git", "arrno/bfast", "https://github.com/arrno/bfast", "arrno%2Fbfast"},
+		{"git@gitlab.com:group/sub/project.git", "gitlab.com/group/sub/project", "https://gitlab.com/group/sub/project", "gitlab.com%2Fgroup%2Fsub%2Fproject"},
+		{"ssh://git@codeberg.org/owner/repo.git", "codeberg.org/owner/repo", "https://codeberg.org/owner/repo", "codeberg.org%2Fowner%2Frepo"},
+		{"https://user@bitbucket.org/workspace/repo.git", "bitbucket.org/workspace/repo", "https://bitbucket.org/workspace/repo", "bitbucket.org%2Fworkspace%2Frepo"},
+	}
+	for _, tc := range cases {
+		slug, err := Parse(tc.input)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error: %v", tc.input, err)
+		}
+		if slug.String() != tc.str || slug.RepoURL() != tc.url || slug.Encoded() != tc.encoded {
+			t.Errorf("%s: got %s, %s, %s", tc.input, slug.String(), slug.RepoURL(), slug.Encoded())
+		}
+		if again, err := Parse(slug.String()); err != nil || again != slug {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v", slug.String(), again, err, slug)
+		}
+	}
+}
+
+func TestHostsRecognizeSelfHostedForges(t *testing.T) {
+	hosts, err := ParseHosts("git.example.com=forgejo, code.example.com=GitLab")
+	if err != nil {
+		t.Fatalf("ParseHosts returned error: %v", err)
+	}
+
+	slug, err := hosts.Parse("git@git.example.com:owner/repo.git")
+	if err != nil || slug != (Slug{"git.example.com", "owner", "repo", Gitea}) {
+		t.Fatalf("Parse = %+v, %v", slug, err)
+	}
+	slug, err = hosts.Parse("https://code.example.com/a/b/c")
+	if err != nil || slug != (Slug{"code.example.com", "a/b", "c", GitLab}) {
+		t.Fatalf("Parse = %+v, %v", slug, err)
+	}
+
+	for _, spec := range []string{"git.example.com", "git.example.com=svn"} {
+		if _, err := ParseHosts(spec); err == nil {
+			t.Errorf("ParseHosts(%q) succeeded, want error", spec)
+		}
+	}
+}
@@ -3,8 +3,12 @@ package readme
 import (
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -32,15 +36,23 @@ func FindDefault(root string) (string, error) {
 		path := filepath.Join(root, name)
 		info, err := os.Stat(path)
 		if err == nil && !info.IsDir() {
+			slog.Debug("found README", "path", path)
 			return path, nil
 		}
+		slog.Debug("README candidate not found", "path", path)
 	}
 
 	return "", ErrNotFound
 }
 
-// HasBadge reports whether the README already contains the blazingly.fast badge.
+// HasBadge reports whether the README already contains the blazingly.fast
+// badge: any badge FindBadges sees, or the badge image URL or badge text
+// anywhere else in the content.
 func HasBadge(content string) bool {
+	if len(FindBadges(content)) > 0 {
+		return true
+	}
+
 	lower := strings.ToLower(content)
 	if strings.Contains(lower, strings.ToLower(BadgeImageURL)) {
 		return true
@@ -55,8 +67,37 @@ func HasBadge(content string) bool {
 	return false
 }
 
+// Placement selects where InsertBadgeAt puts a new badge.
+type Placement string
+
+// Supported badge placements.
+const (
+	PlaceAuto   Placement = "auto"   // existing badge block, else after the title, else top
+	PlaceTop    Placement = "top"    // first line of the file
+	PlaceTitle  Placement = "title"  // line after the first "# " heading, else top
+	PlaceBottom Placement = "bottom" // last line of the file
+)
+
+// ParsePlacement validates a placement name. An empty name means PlaceAuto.
+func ParsePlacement(name string) (Placement, error) {
+	switch p := Placement(strings.ToLower(strings.TrimSpace(name))); p {
+	case "":
+		return PlaceAuto, nil
+	case PlaceAuto, PlaceTop, PlaceTitle, PlaceBottom:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown badge placement %q (want auto, top, title, or bottom)", name)
+	}
+}
+
 // InsertBadge returns README content with the badge inserted.
 func InsertBadge(content, badge string) (string, error) {
+	return InsertBadgeAt(content, badge, PlaceAuto)
+}
+
+// InsertBadgeAt returns README content with the badge inserted at the given
+// placement.
+func InsertBadgeAt(content, badge string, placement Placement) (string, error) {
 	if strings.TrimSpace(badge) == "" {
 		return "", errors.New("badge content may not be empty")
 	}
@@ -69,13 +110,29 @@ func InsertBadge(content, badge string) (string, error) {
 		lines = []string{""}
 	}
 
+	switch placement {
+	case PlaceAuto, "":
+		lines = insertAuto(lines, badge)
+	case PlaceTop:
+		lines = append([]string{badge, ""}, lines...)
+	case PlaceTitle:
+		lines = insertAfterTitle(lines, badge)
+	case PlaceBottom:
+		lines = insertBottom(lines, badge)
+	default:
+		return "", fmt.Errorf("unknown badge placement %q", placement)
+	}
+
+	return formatOutput(lines, newline), nil
+}
+
+func insertAuto(lines []string, badge string) []string {
 	titleIdx := findTitle(lines)
 	if titleIdx >= 0 && lineContainsBadge(lines[titleIdx]) {
 		lines[titleIdx] = appendInlineBadge(lines[titleIdx], badge)
-		return formatOutput(lines, newline), nil
+		return lines
 	}
 
-	insertIdx := -1
 	blockStart, blockEnd, ok := findBadgeBlock(lines)
 	if !ok && titleIdx >= 0 && titleIdx+1 < len(lines) {
 		if postStart, postEnd, postOK := findBadgeBlock(lines[titleIdx+1:]); postOK {
@@ -88,24 +145,262 @@ func InsertBadge(content, badge string) (string, error) {
 	if ok {
 		if blockStart == blockEnd {
 			lines[blockEnd] = appendInlineBadge(lines[blockEnd], badge)
-			return formatOutput(lines, newline), nil
+			return lines
 		}
-		insertIdx = blockEnd + 1
-	} else if titleIdx >= 0 {
-		insertIdx = titleIdx + 1
-		if insertIdx < len(lines) && strings.TrimSpace(lines[insertIdx]) != "" {
-			lines = insertLine(lines, insertIdx, "")
-			insertIdx++
+		return insertLine(lines, blockEnd+1, badge)
+	}
+
+	return insertAfterTitle(lines, badge)
+}
+
+func insertAfterTitle(lines []string, badge string) []string {
+	titleIdx := findTitle(lines)
+	if titleIdx < 0 {
+		return append([]string{badge, ""}, lines...)
+	}
+
+	insertIdx := titleIdx + 1
+	if insertIdx < len(lines) && strings.TrimSpace(lines[insertIdx]) != "" {
+		lines = insertLine(lines, insertIdx, "")
+		insertIdx++
+	}
+	return insertLine(lines, insertIdx, badge)
+}
+
+func insertBottom(lines []string, badge string) []string {
+	end := len(lines)
+	for end > 0 && isBlank(lines[end-1]) {
+		end--
+	}
+	lines = lines[:end]
+	if end > 0 {
+		lines = append(lines, "")
+	}
+	return append(lines, badge)
+}
+
+// Badge describes a blazingly.fast badge found in README content.
+type Badge struct {
+	Line int    // 1-based line number
+	Text string // badge markup as written in the README
+	Repo string // decoded repo= query parameter of the badge image, if any
+}
+
+// linkTarget matches the (destination "title") or [id] part of a Markdown
+// image or link on one line. The destination may hold balanced parentheses
+// and the optional title may hold any, so the match ends at the ")" that
+// closes it.
+const linkTarget = `(?:\([ \t]*(?:[^\s()]|\([^\s()]*\))*(?:[ \t]+(?:"[^"\n]*"|'[^'\n]*'|\([^()\n]*\)))?[ \t]*\)|\[[^\]\n]*\])`
+
+// badgePatterns match badge markup, most specific first. Later patterns only
+// apply to text not already claimed by an earlier match. Markdown stays on
+// one line; HTML tags may span several.
+var badgePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\[!\[[^\]\n]*\]` + linkTarget + `\]` + linkTarget),
+	regexp.MustCompile(`(?i)<a\s[^>]*>\s*<img\s[^>]*>\s*</a>`),
+	regexp.MustCompile(`(?i)<img\s[^>]*>`),
+	regexp.MustCompile(`!\[[^\]\n]*\]` + linkTarget),
+}
+
+// refDefinition matches a reference definition line such as
+// "[bf]: https://www.blazingly.fast/api/badge.svg?repo=x".
+var refDefinition = regexp.MustCompile(`^ {0,3}\[([^\]]+)\]:\s*(\S+)`)
+
+// refUse matches the [id] part of a reference-style image or link.
+var refUse = regexp.MustCompile(`\]\[([^\]]+)\]`)
+
+// refDefinitions maps the lowercased ids of the reference definitions in
+// lines to their URLs. The first definition of an id wins, as in Markdown.
+func refDefinitions(lines []string) map[string]string {
+	defs := map[string]string{}
+	for _, line := range lines {
+		if m := refDefinition.FindStringSubmatch(line); m != nil {
+			id := strings.ToLower(m[1])
+			if _, ok := defs[id]; !ok {
+				defs[id] = m[2]
+			}
 		}
 	}
+	return defs
+}
 
-	if insertIdx == -1 {
-		lines = append([]string{badge, ""}, lines...)
-	} else {
-		lines = insertLine(lines, insertIdx, badge)
+// refIDs returns the lowercased reference ids used in markup.
+func refIDs(markup string) []string {
+	var ids []string
+	for _, m := range refUse.FindAllStringSubmatch(markup, -1) {
+		ids = append(ids, strings.ToLower(m[1]))
 	}
+	return ids
+}
 
-	return formatOutput(lines, newline), nil
+// resolveRefs appends the URLs of the references in markup so that
+// reference-style badges can be recognized and their repo= read like inline
+// ones.
+func resolveRefs(markup string, defs map[string]string) string {
+	for _, id := range refIDs(markup) {
+		if url, ok := defs[id]; ok {
+			markup += " (" + url + ")"
+		}
+	}
+	return markup
+}
+
+// FindBadges returns every blazingly.fast badge in the content in document
+// order. A badge spanning several lines, such as a multi-line <img> tag, is
+// reported at the line it starts on.
+func FindBadges(content string) []Badge {
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	defs := refDefinitions(strings.Split(normalized, "\n"))
+	var badges []Badge
+	for _, sp := range findBadgeSpans(normalized, defs) {
+		text := normalized[sp[0]:sp[1]]
+		line := strings.Count(normalized[:sp[0]], "\n") + 1
+		badges = append(badges, Badge{Line: line, Text: text, Repo: badgeRepo(resolveRefs(text, defs))})
+	}
+	return badges
+}
+
+var badgeQuery = regexp.MustCompile(`(?i)blazingly\.fast/api/badge\.svg\?([^)"'\s>]*)`)
+
+// badgeRepo extracts the repo= parameter from the badge image URL in markup.
+func badgeRepo(markup string) string {
+	m := badgeQuery.FindStringSubmatch(markup)
+	if m == nil {
+		return ""
+	}
+	query, err := url.ParseQuery(strings.ReplaceAll(m[1], "&amp;", "&"))
+	if err != nil {
+		return ""
+	}
+	return query.Get("repo")
+}
+
+// RemoveBadge strips every blazingly.fast badge from the content and reports
+// how many were removed. Reference definitions used only by removed badges
+// go with them. Lines left empty by the removal are dropped, along with any
+// blank line that would otherwise double up. Content without a badge is
+// returned unchanged.
+func RemoveBadge(content string) (string, int) {
+	newline := detectNewline(content)
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	defs := refDefinitions(strings.Split(normalized, "\n"))
+
+	spans := findBadgeSpans(normalized, defs)
+	if len(spans) == 0 {
+		return content, 0
+	}
+
+	// Cut the badges from the last to the first so that earlier offsets stay
+	// valid. Lines a badge spanned become one line, marked for dropping when
+	// nothing else is left on it.
+	orphaned := map[string]bool{}
+	for j := len(spans) - 1; j >= 0; j-- {
+		start, end := spans[j][0], spans[j][1]
+		for _, id := range refIDs(normalized[start:end]) {
+			orphaned[id] = true
+		}
+
+		lineStart := strings.LastIndex(normalized[:start], "\n") + 1
+		lineEnd := len(normalized)
+		if k := strings.Index(normalized[end:], "\n"); k >= 0 {
+			lineEnd = end + k
+		}
+		line := cutSpan(normalized[lineStart:lineEnd], start-lineStart, end-lineStart)
+		if isBlank(line) {
+			line = droppedLine
+		}
+		normalized = normalized[:lineStart] + line + normalized[lineEnd:]
+	}
+
+	lines := strings.Split(normalized, "\n")
+	for i := 0; i < len(lines); i++ {
+		if lines[i] == droppedLine {
+			lines = dropLine(lines, i)
+			i--
+		}
+	}
+
+	// Keep definitions that something else still refers to.
+	for _, line := range lines {
+		if refDefinition.MatchString(line) {
+			continue
+		}
+		for _, id := range refIDs(line) {
+			delete(orphaned, id)
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		if m := refDefinition.FindStringSubmatch(lines[i]); m != nil && orphaned[strings.ToLower(m[1])] {
+			lines = dropLine(lines, i)
+			i--
+		}
+	}
+
+	return formatOutput(lines, newline), len(spans)
+}
+
+// droppedLine marks a line RemoveBadge emptied. It cannot occur in text.
+const droppedLine = "\x00"
+
+// dropLine removes lines[i], along with a blank line that would otherwise
+// double up or trail the content.
+func dropLine(lines []string, i int) []string {
+	lines = append(lines[:i], lines[i+1:]...)
+	switch {
+	case i < len(lines) && isBlank(lines[i]) && (i == 0 || isBlank(lines[i-1])):
+		lines = append(lines[:i], lines[i+1:]...)
+	case i == len(lines) && i > 0 && isBlank(lines[i-1]):
+		lines = lines[:i-1]
+	}
+	return lines
+}
+
+// findBadgeSpans returns the [start, end) offsets of blazingly.fast badges in
+// text, sorted by position. Reference-style badges are resolved through defs.
+func findBadgeSpans(text string, defs map[string]string) [][2]int {
+	var spans [][2]int
+	for _, pattern := range badgePatterns {
+		for _, loc := range pattern.FindAllStringIndex(text, -1) {
+			if overlapsSpan(spans, loc[0], loc[1]) || !isBlazinglyBadge(resolveRefs(text[loc[0]:loc[1]], defs)) {
+				continue
+			}
+			spans = append(spans, [2]int{loc[0], loc[1]})
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	return spans
+}
+
+func overlapsSpan(spans [][2]int, start, end int) bool {
+	for _, sp := range spans {
+		if start < sp[1] && end > sp[0] {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlazinglyBadge(markup string) bool {
+	lower := strings.ToLower(markup)
+	if strings.Contains(lower, strings.ToLower(BadgeImageURL)) {
+		return true
+	}
+	return strings.Contains(lower, "blazingly fast") && strings.Contains(lower, "blazingly.fast")
+}
+
+// cutSpan removes line[start:end] together with the whitespace that separated
+// it from its neighbours, keeping any leading indentation intact.
+func cutSpan(line string, start, end int) string {
+	before := strings.TrimRight(line[:start], " \t")
+	if strings.TrimSpace(before) != "" {
+		return before + line[end:]
+	}
+	return line[:start] + strings.TrimLeft(line[end:], " \t")
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
 }
 
 func detectNewline(content string) string {
@@ -16,6 +16,8 @@ func TestHasBadge(t *testing.T) {
 	}{
 		{"image", "[![blazingly fast](https://blazingly.fast/api/badge.svg?repo=x%2Fy)](https://blazingly.fast)", true},
 		{"alt text", "[![Certified blazingly fast](https://example.com)](https://blazingly.fast)", true},
+		{"multi-line img", "<p align=\"center\">\n  <img\n    src=\"https://www.blazingly.fast/api/badge.svg?repo=x%2Fy\"\n    alt=\"blazingly fast\">\n</p>", true},
+		{"bare url", "Badge: https://www.blazingly.fast/api/badge.svg?repo=x%2Fy", true},
 		{"absent", "# Title\nSome text", false},
 	}
 
@@ -50,7 +52,7 @@ func TestInsertBadgePreservesWindowsNewlines(t *testing.T) {
 
 func TestInsertBadgeFormattingFixtures(t *testing.T) {
 	badge := "[![blazingly fast](https://blazingly.fast/api/badge.svg?repo=proj)](https://blazingly.fast)"
-	for _, tc := range loadInsertBadgeCases(t) {
+	for _, tc := range loadBadgeCases(t, "insert_badge_cases.txt") {
 		t.Run(tc.Name, func(t *testing.T) {
 			updated, err := InsertBadge(tc.Before, badge)
 			if err != nil {
@@ -64,15 +66,146 @@ func TestInsertBadgeFormattingFixtures(t *testing.T) {
 	}
 }
 
-type insertBadgeCase struct {
+func TestInsertBadgeAtPlacements(t *testing.T) {
+	badge := "[![blazingly fast](https://blazingly.fast/api/badge.svg?repo=proj)](https://blazingly.fast)"
+	content := "[![ci](ci)](ci)\n\n# Project\nSome text\n\n"
+
+	cases := []struct {
+		placement Placement
+		want      string
+	}{
+		{PlaceAuto, "[![ci](ci)](ci) " + badge + "\n\n# Project\nSome text\n\n"},
+		{PlaceTop, badge + "\n\n[![ci](ci)](ci)\n\n# Project\nSome text\n\n"},
+		{PlaceTitle, "[![ci](ci)](ci)\n\n# Project\n\n" + badge + "\nSome text\n\n"},
+		{PlaceBottom, "[![ci](ci)](ci)\n\n# Project\nSome text\n\n" + badge + "\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(string(tc.placement), func(t *testing.T) {
+			got, err := InsertBadgeAt(content, badge, tc.placement)
+			if err != nil {
+				t.Fatalf("InsertBadgeAt returned error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("InsertBadgeAt(%s) = %q, want %q", tc.placement, got, tc.want)
+			}
+		})
+	}
+
+	if _, err := ParsePlacement("sideways"); err == nil {
+		t.Fatal("expected error for unknown placement")
+	}
+}
+
+func TestRemoveBadgeFormattingFixtures(t *testing.T) {
+	for _, tc := range loadBadgeCases(t, "remove_badge_cases.txt") {
+		t.Run(tc.Name, func(t *testing.T) {
+			updated, removed := RemoveBadge(tc.Before)
+			if removed == 0 {
+				t.Fatalf("RemoveBadge removed nothing")
+			}
+
+			if updated != tc.After {
+				t.Fatalf("RemoveBadge produced unexpected content:\n%s\nwant:\n%s", updated, tc.After)
+			}
+
+			if HasBadge(updated) {
+				t.Fatalf("RemoveBadge left a badge behind:\n%s", updated)
+			}
+		})
+	}
+}
+
+func TestRemoveBadgeReversesInsert(t *testing.T) {
+	badge := "[![blazingly fast](https://blazingly.fast/api/badge.svg?repo=proj)](https://blazingly.fast)"
+	for _, tc := range loadBadgeCases(t, "insert_badge_cases.txt") {
+		t.Run(tc.Name, func(t *testing.T) {
+			updated, removed := RemoveBadge(tc.After)
+			if removed != 1 {
+				t.Fatalf("RemoveBadge removed %d badges, want 1", removed)
+			}
+
+			if updated != tc.Before {
+				t.Fatalf("RemoveBadge did not restore original:\n%s\nwant:\n%s", updated, tc.Before)
+			}
+
+			if strings.Contains(updated, badge) {
+				t.Fatalf("badge still present")
+			}
+		})
+	}
+}
+
+func TestRemoveBadgePreservesWindowsNewlines(t *testing.T) {
+	badge := "[![blazingly fast](https://blazingly.fast/api/badge.svg?repo=proj)](https://blazingly.fast)"
+	content := "# Project\r\n" + badge + "\r\n\r\nSome text\r\n"
+
+	updated, removed := RemoveBadge(content)
+	if removed != 1 {
+		t.Fatalf("RemoveBadge removed %d badges, want 1", removed)
+	}
+
+	want := "# Project\r\n\r\nSome text\r\n"
+	if updated != want {
+		t.Fatalf("RemoveBadge produced unexpected content:\n%q\nwant:\n%q", updated, want)
+	}
+}
+
+func TestRemoveBadgeLeavesUnbadgedContent(t *testing.T) {
+	content := "# Project\n\n[![ci](ci)](ci)\nSome text"
+	updated, removed := RemoveBadge(content)
+	if removed != 0 || updated != content {
+		t.Fatalf("RemoveBadge changed content without a badge: %q (%d)", updated, removed)
+	}
+}
+
+func TestFindBadgesReportsLines(t *testing.T) {
+	content := "# Project [![blazingly fast](https://www.blazingly.fast/api/badge.svg?repo=a%2Fb)](https://www.blazingly.fast)\r\n\r\n" +
+		"[![ci](ci)](ci) <img src=\"https://www.blazingly.fast/api/badge.svg?repo=a%2Fb\">\r\n"
+
+	badges := FindBadges(content)
+	if len(badges) != 2 {
+		t.Fatalf("FindBadges returned %d badges, want 2: %+v", len(badges), badges)
+	}
+	if badges[0].Line != 1 || badges[1].Line != 3 {
+		t.Fatalf("unexpected badge lines: %+v", badges)
+	}
+	if !strings.HasPrefix(badges[1].Text, "<img") {
+		t.Fatalf("unexpected badge text: %q", badges[1].Text)
+	}
+	if badges[0].Repo != "a/b" || badges[1].Repo != "a/b" {
+		t.Fatalf("unexpected badge repos: %+v", badges)
+	}
+}
+
+func TestFindBadgesWithoutRepoParam(t *testing.T) {
+	badges := FindBadges("![blazingly fast](https://www.blazingly.fast/api/badge.svg)\n")
+	if len(badges) != 1 || badges[0].Repo != "" {
+		t.Fatalf("unexpected badges: %+v", badges)
+	}
+}
+
+func TestFindBadgesResolvesReferences(t *testing.T) {
+	content := "# Project\n\n[![blazingly fast][bf]][site]\n\n[BF]: https://www.blazingly.fast/api/badge.svg?repo=a%2Fb\n[site]: https://www.blazingly.fast\n"
+
+	badges := FindBadges(content)
+	if len(badges) != 1 || badges[0].Line != 3 || badges[0].Repo != "a/b" {
+		t.Fatalf("unexpected badges: %+v", badges)
+	}
+	if !HasBadge(content) {
+		t.Fatal("HasBadge missed the reference-style badge")
+	}
+}
+
+type badgeCase struct {
 	Name   string
 	Before string
 	After  string
 }
 
-func loadInsertBadgeCases(t *testing.T) []insertBadgeCase {
+func loadBadgeCases(t *testing.T, name string) []badgeCase {
 	t.Helper()
-	path := filepath.Join("testdata", "insert_badge_cases.txt")
+	path := filepath.Join("testdata", name)
 	f, err := os.Open(path)
 	if err != nil {
 		t.Fatalf("open testdata: %v", err)
@@ -80,8 +213,8 @@ func loadInsertBadgeCases(t *testing.T) []insertBadgeCase {
 	defer f.Close()
 
 	scanner := bufio.NewScanner(f)
-	var cases []insertBadgeCase
-	var current *insertBadgeCase
+	var cases []badgeCase
+	var current *badgeCase
 	var mode string
 	var buf strings.Builder
 
@@ -108,7 +241,7 @@ func loadInsertBadgeCases(t *testing.T) []insertBadgeCase {
 				t.Fatalf("started new case before closing %q", current.Name)
 			}
 			name := strings.TrimSuffix(strings.TrimPrefix(line, "=== case:"), " ===")
-			current = &insertBadgeCase{Name: strings.TrimSpace(name)}
+			current = &badgeCase{Name: strings.TrimSpace(name)}
 		case line == "--- before ---":
 			if current == nil {
 				t.Fatalf("encountered before block without active case")
@@ -142,7 +275,7 @@ func loadInsertBadgeCases(t *testing.T) []insertBadgeCase {
 	}
 
 	if len(cases) == 0 {
-		t.Fatalf("no insert badge cases loaded")
+		t.Fatalf("no badge cases loaded from %s", name)
 	}
 
 	return cases